@@ -0,0 +1,351 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ConnectionSettings secures the connection to the collector, shared by the OTLP gRPC
+// exporters and the OTLP/HTTP trace exporter. Files are read again when they change,
+// so rotated certificates and tokens are picked up without a restart.
+// ConnectionSettings protege a conexão com o collector, compartilhada pelos exportadores
+// OTLP gRPC e pelo exportador de traces OTLP/HTTP. Os arquivos são lidos de novo quando
+// mudam, então certificados e tokens rotacionados são usados sem reiniciar.
+type ConnectionSettings struct {
+	Insecure    bool              // Plaintext connection, the other TLS settings are ignored
+	CAFile      string            // PEM bundle trusted for the collector certificate, empty uses the system roots
+	CertFile    string            // PEM client certificate for mTLS
+	KeyFile     string            // PEM private key of CertFile
+	ServerName  string            // Name checked against the collector certificate, empty uses the endpoint host
+	Headers     map[string]string // Static headers sent with every export, e.g. an API key
+	HeadersFile string            // File with "name=value" lines sent with every export, e.g. a bearer token
+}
+
+// grpcOptions returns the transport credentials and per-RPC headers for the collector
+// connection to endpoint, e.g. "otel-collector:4317".
+// Retorna as credenciais de transporte e os cabeçalhos por RPC da conexão com o collector
+// em endpoint, ex.: "otel-collector:4317".
+func (s ConnectionSettings) grpcOptions(endpoint string) ([]grpc.DialOption, error) {
+	transport := insecure.NewCredentials()
+	tlsConfig, err := s.tlsConfig(endpointHost(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport = credentials.NewTLS(tlsConfig)
+	}
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+
+	headers, err := s.headerSource()
+	if err != nil {
+		return nil, err
+	}
+	if headers != nil {
+		options = append(options, grpc.WithPerRPCCredentials(headers))
+	}
+	return options, nil
+}
+
+// httpClient returns a client for the OTLP/HTTP exporter at endpoint with the same TLS
+// settings and headers as the gRPC connection. Whether TLS is used follows the endpoint scheme.
+// Retorna um cliente para o exportador OTLP/HTTP em endpoint com as mesmas configurações
+// de TLS e cabeçalhos da conexão gRPC. O uso de TLS segue o esquema do endpoint.
+func (s ConnectionSettings) httpClient(endpoint string) (*http.Client, error) {
+	tlsConfig, err := s.tlsConfig(endpointHost(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	headers, err := s.headerSource()
+	if err != nil {
+		return nil, err
+	}
+	if headers == nil {
+		return &http.Client{Transport: transport}, nil
+	}
+	return &http.Client{Transport: headerTransport{transport, headers}}, nil
+}
+
+// tlsConfig builds the client TLS configuration for the collector at host, nil for a
+// plaintext connection. The CA bundle and the client certificate are checked for
+// changes on each handshake.
+// Monta a configuração TLS do cliente para o collector em host, nil para uma conexão
+// sem TLS. O bundle de CAs e o certificado do cliente são verificados a cada handshake.
+func (s ConnectionSettings) tlsConfig(host string) (*tls.Config, error) {
+	if s.Insecure {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: s.ServerName}
+
+	if s.CertFile != "" || s.KeyFile != "" {
+		pair := newReloadingFile(func(content [][]byte) (tls.Certificate, error) {
+			return tls.X509KeyPair(content[0], content[1])
+		}, s.CertFile, s.KeyFile)
+		if _, err := pair.get(); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, err := pair.get()
+			return &certificate, err
+		}
+	}
+
+	if s.CAFile != "" {
+		roots := newReloadingFile(func(content [][]byte) (*x509.CertPool, error) {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(content[0]) {
+				return nil, errors.New("no PEM certificate found")
+			}
+			return pool, nil
+		}, s.CAFile)
+		if _, err := roots.get(); err != nil {
+			return nil, fmt.Errorf("failed to load CA bundle: %w", err)
+		}
+		// The SNI in the connection state is empty for an IP endpoint, so the name to
+		// check is fixed here
+		// O SNI no estado da conexão é vazio para um endpoint IP, então o nome a
+		// verificar é definido aqui
+		name := s.ServerName
+		if name == "" {
+			name = host
+		}
+		if name == "" {
+			return nil, errors.New("no name to verify the collector certificate against, set the server name")
+		}
+		// The standard verification uses a fixed RootCAs pool, so verify by hand against
+		// the current bundle instead
+		// A verificação padrão usa um pool RootCAs fixo, então verifica manualmente
+		// contra o bundle atual
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			pool, err := roots.get()
+			if err != nil {
+				return err
+			}
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("collector sent no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, certificate := range state.PeerCertificates[1:] {
+				intermediates.AddCert(certificate)
+			}
+			_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         pool,
+				Intermediates: intermediates,
+				DNSName:       name,
+			})
+			return err
+		}
+	}
+	return config, nil
+}
+
+// endpointHost returns the host of a collector endpoint, either "host:port" or a URL
+// such as "https://host:4318/v1/traces" or the gRPC target "dns:///host:4317".
+// Retorna o host de um endpoint do collector, seja "host:porta" ou uma URL como
+// "https://host:4318/v1/traces" ou o alvo gRPC "dns:///host:4317".
+func endpointHost(endpoint string) string {
+	if _, rest, found := strings.Cut(endpoint, "://"); found {
+		if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+			return parsed.Hostname()
+		}
+		endpoint = strings.TrimPrefix(rest, "/")
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return endpoint
+}
+
+// headerSource returns the headers to send with every export, nil when there are none.
+// Retorna os cabeçalhos enviados em toda exportação, nil quando não há nenhum.
+func (s ConnectionSettings) headerSource() (*headerSource, error) {
+	if len(s.Headers) == 0 && s.HeadersFile == "" {
+		return nil, nil
+	}
+	source := &headerSource{static: map[string]string{}}
+	for name, value := range s.Headers {
+		source.static[strings.ToLower(name)] = value // gRPC metadata keys are lowercase
+	}
+	if s.HeadersFile != "" {
+		source.file = newReloadingFile(func(content [][]byte) (map[string]string, error) {
+			return parseHeaders(content[0])
+		}, s.HeadersFile)
+		if _, err := source.file.get(); err != nil {
+			return nil, fmt.Errorf("failed to load headers file: %w", err)
+		}
+	}
+	return source, nil
+}
+
+// parseHeaders reads "name=value" lines, skipping blank lines and # comments.
+// Lê linhas "nome=valor", ignorando linhas em branco e comentários com #.
+func parseHeaders(content []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("header line %q, expected name=value", line)
+		}
+		headers[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	return headers, scanner.Err()
+}
+
+// headerSource merges the static headers with the current content of the headers file.
+// It implements credentials.PerRPCCredentials for gRPC.
+// Junta os cabeçalhos estáticos com o conteúdo atual do arquivo de cabeçalhos.
+// Implementa credentials.PerRPCCredentials para o gRPC.
+type headerSource struct {
+	static map[string]string
+	file   *reloadingFile[map[string]string] // Nil without a headers file
+}
+
+func (h *headerSource) headers() (map[string]string, error) {
+	if h.file == nil {
+		return h.static, nil
+	}
+	fromFile, err := h.file.get()
+	if err != nil {
+		return nil, err
+	}
+	headers := maps.Clone(h.static)
+	maps.Copy(headers, fromFile)
+	return headers, nil
+}
+
+func (h *headerSource) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return h.headers()
+}
+
+// RequireTransportSecurity allows headers over plaintext too, for local collectors.
+// Permite cabeçalhos também sem TLS, para collectors locais.
+func (h *headerSource) RequireTransportSecurity() bool {
+	return false
+}
+
+// headerTransport adds the export headers to every HTTP request.
+// Adiciona os cabeçalhos de exportação em toda requisição HTTP.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers *headerSource
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, err := t.headers.headers()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the base transport.
+// Permite que http.Client.CloseIdleConnections alcance o transporte base.
+func (t headerTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// reloadingFile keeps the value parsed from one or more files and parses them again
+// when any of them changes. If the new content is invalid, e.g. while a rotation is
+// half written, the previous value is kept and a warning is logged.
+// Guarda o valor lido de um ou mais arquivos e os lê de novo quando algum deles muda.
+// Se o novo conteúdo for inválido, ex.: durante uma rotação escrita pela metade, o
+// valor anterior é mantido e um aviso é registrado.
+type reloadingFile[T any] struct {
+	paths []string
+	parse func([][]byte) (T, error)
+
+	mu      sync.Mutex
+	version string // Modification times and sizes of the loaded files
+	value   T
+	loaded  bool
+	failure string // Last reload failure, logged once
+}
+
+func newReloadingFile[T any](parse func([][]byte) (T, error), paths ...string) *reloadingFile[T] {
+	return &reloadingFile[T]{paths: paths, parse: parse}
+}
+
+func (f *reloadingFile[T]) get() (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	version, err := f.stat()
+	if err == nil && f.loaded && version == f.version {
+		return f.value, nil
+	}
+	if err == nil {
+		var value T
+		if value, err = f.read(); err == nil {
+			if f.loaded {
+				slog.Info("telemetry connection file reloaded", "files", f.paths)
+			}
+			f.value, f.version, f.loaded = value, version, true
+			return value, nil
+		}
+	}
+	if !f.loaded {
+		return f.value, err
+	}
+	if failure := version + err.Error(); failure != f.failure {
+		f.failure = failure
+		slog.Warn("telemetry connection file reload failed, keeping the previous content", "files", f.paths, "error", err)
+	}
+	return f.value, nil
+}
+
+func (f *reloadingFile[T]) stat() (string, error) {
+	var version strings.Builder
+	for _, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return version.String(), nil
+}
+
+func (f *reloadingFile[T]) read() (T, error) {
+	contents := make([][]byte, len(f.paths))
+	for i, path := range f.paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		contents[i] = content
+	}
+	return f.parse(contents)
+}
@@ -0,0 +1,294 @@
+package telemetry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the connection tests.
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, key := createCertificate(t, template, nil, nil)
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{certificate: certificate, key: key, pem: pemBlock("CERTIFICATE", der)}
+}
+
+// issue returns a certificate and its key in PEM, valid for the given DNS names and IPs.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage, names ...string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	der, key := createCertificate(t, template, ca.certificate, ca.key)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemBlock("CERTIFICATE", der), pemBlock("EC PRIVATE KEY", keyDER)
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der, key
+}
+
+func pemBlock(kind string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+}
+
+// writeFile writes content to dir/name, moving the modification time forward so a
+// rewrite of the same size is still seen as a change.
+func writeFile(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	previous, statErr := os.Stat(path)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if statErr == nil {
+		later := previous.ModTime().Add(time.Second)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// newTLSCollector starts an HTTPS server with the given certificate, answering with
+// the common name of the client certificate and the value of the Authorization header.
+func newTLSCollector(t *testing.T, certPEM, keyPEM []byte, clientCAs *x509.CertPool) *httptest.Server {
+	t.Helper()
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("Client-Name", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		w.Header().Set("Client-Authorization", r.Header.Get("Authorization"))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	if clientCAs != nil {
+		server.TLS.ClientCAs = clientCAs
+		server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get sends a request on a new connection, so every call makes a handshake.
+func get(t *testing.T, settings ConnectionSettings, url string) (*http.Response, error) {
+	t.Helper()
+	client, err := settings.httpClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestTLSWithCAOnlyChecksTheEndpointHost(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+
+	// A certificate from the trusted CA for another name must be rejected even
+	// though the collector is reached by IP, which sends no SNI
+	certPEM, keyPEM := ca.issue(t, "collector", x509.ExtKeyUsageServerAuth, "collector.test")
+	server := newTLSCollector(t, certPEM, keyPEM, nil)
+	if _, err := get(t, ConnectionSettings{CAFile: caFile}, server.URL); err == nil {
+		t.Error("certificate for collector.test accepted for an IP endpoint")
+	}
+
+	// The configured server name replaces the endpoint host
+	if _, err := get(t, ConnectionSettings{CAFile: caFile, ServerName: "collector.test"}, server.URL); err != nil {
+		t.Errorf("certificate for the configured server name rejected: %v", err)
+	}
+
+	// Without a server name the endpoint IP is checked
+	certPEM, keyPEM = ca.issue(t, "collector", x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	server = newTLSCollector(t, certPEM, keyPEM, nil)
+	if _, err := get(t, ConnectionSettings{CAFile: caFile}, server.URL); err != nil {
+		t.Errorf("certificate for the endpoint IP rejected: %v", err)
+	}
+
+	// A certificate from another CA is rejected
+	certPEM, keyPEM = newTestCA(t).issue(t, "collector", x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	server = newTLSCollector(t, certPEM, keyPEM, nil)
+	if _, err := get(t, ConnectionSettings{CAFile: caFile}, server.URL); err == nil {
+		t.Error("certificate from an untrusted CA accepted")
+	}
+}
+
+func TestTLSWithCAOnlyNeedsAName(t *testing.T) {
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", newTestCA(t).pem)
+
+	if _, err := (ConnectionSettings{CAFile: caFile}).tlsConfig(""); err == nil {
+		t.Error("expected an error without a server name nor an endpoint host")
+	}
+	if _, err := (ConnectionSettings{CAFile: caFile, ServerName: "collector.test"}).tlsConfig(""); err != nil {
+		t.Errorf("unexpected error with a server name: %v", err)
+	}
+}
+
+func TestMutualTLSReloadsTheRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.certificate)
+
+	certPEM, keyPEM := ca.issue(t, "service-a", x509.ExtKeyUsageClientAuth)
+	settings := ConnectionSettings{
+		CAFile:      writeFile(t, dir, "ca.pem", ca.pem),
+		CertFile:    writeFile(t, dir, "client.pem", certPEM),
+		KeyFile:     writeFile(t, dir, "client.key", keyPEM),
+		HeadersFile: writeFile(t, dir, "headers", []byte("Authorization=Bearer first\n")),
+	}
+	serverCert, serverKey := ca.issue(t, "collector", x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	server := newTLSCollector(t, serverCert, serverKey, clientCAs)
+
+	client, err := settings.httpClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func() *http.Response {
+		t.Helper()
+		client.CloseIdleConnections() // Handshake again to pick the current files
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := request()
+	if got := resp.Header.Get("Client-Name"); got != "service-a" {
+		t.Errorf("client certificate = %q, want service-a", got)
+	}
+	if got := resp.Header.Get("Client-Authorization"); got != "Bearer first" {
+		t.Errorf("authorization = %q, want Bearer first", got)
+	}
+
+	// Rotate the client certificate and the token
+	certPEM, keyPEM = ca.issue(t, "service-a-rotated", x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "client.pem", certPEM)
+	writeFile(t, dir, "client.key", keyPEM)
+	writeFile(t, dir, "headers", []byte("Authorization=Bearer second\n"))
+
+	resp = request()
+	if got := resp.Header.Get("Client-Name"); got != "service-a-rotated" {
+		t.Errorf("client certificate = %q after the rotation, want service-a-rotated", got)
+	}
+	if got := resp.Header.Get("Client-Authorization"); got != "Bearer second" {
+		t.Errorf("authorization = %q after the rotation, want Bearer second", got)
+	}
+
+	// A half written file keeps the previous certificate
+	writeFile(t, dir, "client.pem", []byte("-----BEGIN CERTIFICATE-----\n"))
+	resp = request()
+	if got := resp.Header.Get("Client-Name"); got != "service-a-rotated" {
+		t.Errorf("client certificate = %q after an invalid rotation, want the previous one", got)
+	}
+}
+
+func TestTLSReloadsTheRotatedCA(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	settings := ConnectionSettings{CAFile: writeFile(t, dir, "ca.pem", oldCA.pem)}
+
+	certPEM, keyPEM := newCA.issue(t, "collector", x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	server := newTLSCollector(t, certPEM, keyPEM, nil)
+	client, err := settings.httpClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("certificate from a CA not yet trusted accepted")
+	}
+
+	writeFile(t, dir, "ca.pem", newCA.pem)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("certificate from the rotated CA rejected: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestEndpointHost(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"otel-collector:4317":                   "otel-collector",
+		"10.0.0.7:4317":                         "10.0.0.7",
+		"[::1]:4317":                            "::1",
+		"dns:///otel-collector:4317":            "otel-collector",
+		"https://otel-collector:4318/v1/traces": "otel-collector",
+		"http://10.0.0.7:4318/v1/traces":        "10.0.0.7",
+		"otel-collector":                        "otel-collector",
+		"":                                      "",
+	} {
+		if got := endpointHost(endpoint); got != want {
+			t.Errorf("endpointHost(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
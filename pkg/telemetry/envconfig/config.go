@@ -0,0 +1,293 @@
+package envconfig
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	"telemetry"
+)
+
+// Telemetry groups the telemetry settings of a service. It is embedded inline in
+// each service configuration, so its sections stay at the top level of the YAML file.
+// Agrupa as configurações de telemetria de um serviço. É incorporado inline na
+// configuração de cada serviço, então suas seções ficam no nível raiz do arquivo YAML.
+type Telemetry struct {
+	Resource ResourceConfig `yaml:"resource"`
+	OTLP     OTLPConfig     `yaml:"otlp"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// DefaultTelemetry returns the telemetry settings used when nothing is set.
+// Retorna as configurações de telemetria usadas quando nada é definido.
+func DefaultTelemetry() Telemetry {
+	return Telemetry{
+		OTLP: OTLPConfig{Insecure: true},
+
+		Tracing: TracingConfig{
+			Sampler:     "parentbased_always_on",
+			DebugHeader: "X-Debug-Trace",
+
+			TailSampling: TailSamplingConfig(telemetry.DefaultTailSamplingSettings()),
+
+			Exporters:        []string{"otlp"},
+			OTLPHTTPEndpoint: "http://otel-collector:4318/v1/traces",
+			ZipkinEndpoint:   "http://zipkin:9411/api/v2/spans",
+			FilePath:         "traces.jsonl",
+
+			Propagators:       []string{"tracecontext", "baggage"},
+			BaggageAttributes: []string{"tenant.id", "client.id"},
+		},
+		Metrics: MetricsConfig{
+			Exporters:      []string{"otlp"},
+			PrometheusAddr: ":9464",
+		},
+		Log: LogConfig{
+			Level:     "info",
+			Exporters: []string{"none"},
+		},
+	}
+}
+
+// LoadEnv overlays the telemetry variables set in the environment.
+// Sobrepõe as variáveis de telemetria definidas no ambiente.
+func (c *Telemetry) LoadEnv(env *Env) {
+	env.String("SERVICE_VERSION", &c.Resource.Version)
+	env.String("SERVICE_INSTANCE_ID", &c.Resource.InstanceID)
+	env.String("DEPLOYMENT_ENVIRONMENT", &c.Resource.Environment)
+	env.Attributes("OTEL_RESOURCE_ATTRIBUTES", &c.Resource.Attributes)
+	env.Bool("OTLP_INSECURE", &c.OTLP.Insecure)
+	env.String("OTLP_CA_FILE", &c.OTLP.CAFile)
+	env.String("OTLP_CLIENT_CERT_FILE", &c.OTLP.CertFile)
+	env.String("OTLP_CLIENT_KEY_FILE", &c.OTLP.KeyFile)
+	env.String("OTLP_SERVER_NAME", &c.OTLP.ServerName)
+	env.StringMap("OTLP_HEADERS", &c.OTLP.Headers)
+	env.String("OTLP_HEADERS_FILE", &c.OTLP.HeadersFile)
+	env.String("OTEL_TRACES_SAMPLER", &c.Tracing.Sampler)
+	env.String("OTEL_TRACES_SAMPLER_ARG", &c.Tracing.SamplerArg)
+	env.String("TRACE_DEBUG_HEADER", &c.Tracing.DebugHeader)
+	env.Bool("TAIL_SAMPLING_ENABLED", &c.Tracing.TailSampling.Enabled)
+	env.Duration("TAIL_SAMPLING_LATENCY_THRESHOLD", &c.Tracing.TailSampling.LatencyThreshold)
+	env.Float("TAIL_SAMPLING_HEALTHY_RATIO", &c.Tracing.TailSampling.HealthyRatio)
+	env.Duration("TAIL_SAMPLING_DECISION_WAIT", &c.Tracing.TailSampling.DecisionWait)
+	env.Int("TAIL_SAMPLING_MAX_TRACES", &c.Tracing.TailSampling.MaxTraces)
+	env.Int("TAIL_SAMPLING_MAX_SPANS_PER_TRACE", &c.Tracing.TailSampling.MaxSpansPerTrace)
+	env.List("OTEL_TRACES_EXPORTER", &c.Tracing.Exporters)
+	env.String("OTLP_HTTP_ENDPOINT", &c.Tracing.OTLPHTTPEndpoint)
+	env.String("OTEL_EXPORTER_ZIPKIN_ENDPOINT", &c.Tracing.ZipkinEndpoint)
+	env.String("TRACES_FILE", &c.Tracing.FilePath)
+	env.List("OTEL_PROPAGATORS", &c.Tracing.Propagators)
+	env.List("BAGGAGE_SPAN_ATTRIBUTES", &c.Tracing.BaggageAttributes)
+	env.List("OTEL_METRICS_EXPORTER", &c.Metrics.Exporters)
+	env.String("PROMETHEUS_ADDR", &c.Metrics.PrometheusAddr)
+	env.String("LOG_LEVEL", &c.Log.Level)
+	env.List("OTEL_LOGS_EXPORTER", &c.Log.Exporters)
+}
+
+// Validate checks every telemetry section, reporting each problem through check.
+// Valida cada seção de telemetria, reportando cada problema por meio de check.
+func (c Telemetry) Validate(check func(bool, string, ...any)) {
+	c.OTLP.Validate(check)
+	c.Tracing.Validate(check)
+	c.Metrics.Validate(check)
+	c.Log.Validate(check)
+}
+
+// ResourceConfig describes the service instance in every span, metric and log.
+// Descreve a instância do serviço em todo span, métrica e log.
+type ResourceConfig struct {
+	Version     string            `yaml:"version"`     // SERVICE_VERSION, defaults to the version stamped at build time
+	InstanceID  string            `yaml:"instance_id"` // SERVICE_INSTANCE_ID, defaults to a random UUID per process
+	Environment string            `yaml:"environment"` // DEPLOYMENT_ENVIRONMENT, e.g. "production"
+	Attributes  map[string]string `yaml:"attributes"`  // OTEL_RESOURCE_ATTRIBUTES ("key=value", comma separated, percent-encoded values)
+}
+
+// Settings converts the config into the telemetry resource settings.
+// Converte a configuração nas configurações de resource do telemetry.
+func (c ResourceConfig) Settings() telemetry.ResourceSettings {
+	return telemetry.ResourceSettings(c)
+}
+
+// OTLPConfig secures the connection to the collector.
+// Protege a conexão com o collector.
+type OTLPConfig struct {
+	Insecure    bool              `yaml:"insecure"`     // OTLP_INSECURE, plaintext connection (the default, for the local collector)
+	CAFile      string            `yaml:"ca_file"`      // OTLP_CA_FILE, PEM bundle trusted for the collector certificate
+	CertFile    string            `yaml:"cert_file"`    // OTLP_CLIENT_CERT_FILE, PEM client certificate for mTLS
+	KeyFile     string            `yaml:"key_file"`     // OTLP_CLIENT_KEY_FILE, PEM private key of the client certificate
+	ServerName  string            `yaml:"server_name"`  // OTLP_SERVER_NAME, overrides the name checked against the collector certificate
+	Headers     map[string]string `yaml:"headers"`      // OTLP_HEADERS ("name=value", comma separated), static export headers
+	HeadersFile string            `yaml:"headers_file"` // OTLP_HEADERS_FILE, "name=value" lines such as a bearer token, reloaded on change
+}
+
+// Settings converts the config into the telemetry connection settings.
+// Converte a configuração nas configurações de conexão do telemetry.
+func (c OTLPConfig) Settings() telemetry.ConnectionSettings {
+	return telemetry.ConnectionSettings(c)
+}
+
+// Validate checks that the certificate files exist and come in pairs.
+// Valida que os arquivos de certificado existem e vêm em pares.
+func (c OTLPConfig) Validate(check func(bool, string, ...any)) {
+	check((c.CertFile == "") == (c.KeyFile == ""), "OTLP_CLIENT_CERT_FILE and OTLP_CLIENT_KEY_FILE must be set together")
+	for name, path := range map[string]string{
+		"OTLP_CA_FILE":          c.CAFile,
+		"OTLP_CLIENT_CERT_FILE": c.CertFile,
+		"OTLP_CLIENT_KEY_FILE":  c.KeyFile,
+		"OTLP_HEADERS_FILE":     c.HeadersFile,
+	} {
+		if path != "" {
+			_, err := os.Stat(path)
+			check(err == nil, "%s: %v", name, err)
+		}
+	}
+}
+
+// TracingConfig configures trace sampling, export and context propagation.
+// Configura a amostragem, a exportação e a propagação de contexto dos traces.
+type TracingConfig struct {
+	Sampler     string `yaml:"sampler"`      // OTEL_TRACES_SAMPLER
+	SamplerArg  string `yaml:"sampler_arg"`  // OTEL_TRACES_SAMPLER_ARG
+	DebugHeader string `yaml:"debug_header"` // TRACE_DEBUG_HEADER, requests carrying it are always sampled
+
+	TailSampling TailSamplingConfig `yaml:"tail_sampling"`
+
+	Exporters        []string `yaml:"exporters"`          // OTEL_TRACES_EXPORTER ("otlp", "otlphttp", "zipkin", "console", "file" or "none", comma separated)
+	OTLPHTTPEndpoint string   `yaml:"otlp_http_endpoint"` // OTLP_HTTP_ENDPOINT, full URL of the OTLP/HTTP traces endpoint
+	ZipkinEndpoint   string   `yaml:"zipkin_endpoint"`    // OTEL_EXPORTER_ZIPKIN_ENDPOINT, full URL of the Zipkin spans endpoint
+	FilePath         string   `yaml:"file"`               // TRACES_FILE, JSON lines written by the file exporter
+
+	Propagators       []string `yaml:"propagators"`        // OTEL_PROPAGATORS ("tracecontext", "baggage", "b3", "b3multi", "jaeger" or "none", comma separated)
+	BaggageAttributes []string `yaml:"baggage_attributes"` // BAGGAGE_SPAN_ATTRIBUTES, baggage members copied into the request span
+}
+
+// TailSamplingConfig configures the in-process tail sampler.
+// Configura o tail sampler em processo.
+type TailSamplingConfig struct {
+	Enabled          bool          `yaml:"enabled"`             // TAIL_SAMPLING_ENABLED
+	LatencyThreshold time.Duration `yaml:"latency_threshold"`   // TAIL_SAMPLING_LATENCY_THRESHOLD, slower traces are always kept
+	HealthyRatio     float64       `yaml:"healthy_ratio"`       // TAIL_SAMPLING_HEALTHY_RATIO, fraction of healthy traces kept
+	DecisionWait     time.Duration `yaml:"decision_wait"`       // TAIL_SAMPLING_DECISION_WAIT, longest a trace is buffered
+	MaxTraces        int           `yaml:"max_traces"`          // TAIL_SAMPLING_MAX_TRACES, traces buffered at once
+	MaxSpansPerTrace int           `yaml:"max_spans_per_trace"` // TAIL_SAMPLING_MAX_SPANS_PER_TRACE
+}
+
+// Settings converts the config into the telemetry tracing settings.
+// Converte a configuração nas configurações de tracing do telemetry.
+func (c TracingConfig) Settings() telemetry.TracingSettings {
+	return telemetry.TracingSettings{
+		Sampler:      c.Sampler,
+		SamplerArg:   c.SamplerArg,
+		DebugHeader:  c.DebugHeader,
+		TailSampling: telemetry.TailSamplingSettings(c.TailSampling),
+		Export: telemetry.ExporterSettings{
+			Exporters:        c.Exporters,
+			OTLPHTTPEndpoint: c.OTLPHTTPEndpoint,
+			ZipkinEndpoint:   c.ZipkinEndpoint,
+			FilePath:         c.FilePath,
+		},
+
+		Propagators:       c.Propagators,
+		BaggageAttributes: c.BaggageAttributes,
+	}
+}
+
+// Validate checks that the sampler, the exporters and the propagator can be built.
+// Valida que o sampler, os exportadores e o propagador podem ser construídos.
+func (c TracingConfig) Validate(check func(bool, string, ...any)) {
+	_, err := telemetry.NewSampler(c.Sampler, c.SamplerArg)
+	check(err == nil, "%v", err)
+	for _, exporter := range c.Exporters {
+		switch exporter {
+		case "otlp", "console":
+		case "otlphttp":
+			check(IsHTTPURL(c.OTLPHTTPEndpoint), "trace exporter otlphttp needs OTLP_HTTP_ENDPOINT as an absolute http(s) URL, got %q", c.OTLPHTTPEndpoint)
+		case "zipkin":
+			check(IsHTTPURL(c.ZipkinEndpoint), "trace exporter zipkin needs OTEL_EXPORTER_ZIPKIN_ENDPOINT as an absolute http(s) URL, got %q", c.ZipkinEndpoint)
+		case "file":
+			check(c.FilePath != "", "trace exporter file needs TRACES_FILE")
+		case "none":
+			check(len(c.Exporters) == 1, "trace exporter none cannot be combined with other exporters")
+		default:
+			check(false, "unknown trace exporter %q (available: otlp, otlphttp, zipkin, console, file, none)", exporter)
+		}
+	}
+	_, err = telemetry.NewPropagator(c.Propagators)
+	check(err == nil, "OTEL_PROPAGATORS: %v", err)
+
+	if tail := c.TailSampling; tail.Enabled {
+		check(tail.LatencyThreshold > 0, "TAIL_SAMPLING_LATENCY_THRESHOLD must be positive, got %s", tail.LatencyThreshold)
+		check(tail.HealthyRatio >= 0 && tail.HealthyRatio <= 1, "TAIL_SAMPLING_HEALTHY_RATIO must be between 0 and 1, got %v", tail.HealthyRatio)
+		check(tail.DecisionWait > 0, "TAIL_SAMPLING_DECISION_WAIT must be positive, got %s", tail.DecisionWait)
+		check(tail.MaxTraces > 0, "TAIL_SAMPLING_MAX_TRACES must be positive, got %d", tail.MaxTraces)
+		check(tail.MaxSpansPerTrace > 0, "TAIL_SAMPLING_MAX_SPANS_PER_TRACE must be positive, got %d", tail.MaxSpansPerTrace)
+	}
+}
+
+// MetricsConfig selects the metric exporters.
+// Seleciona os exportadores de métricas.
+type MetricsConfig struct {
+	Exporters      []string `yaml:"exporters"`       // OTEL_METRICS_EXPORTER ("otlp", "prometheus" or "none", comma separated)
+	PrometheusAddr string   `yaml:"prometheus_addr"` // PROMETHEUS_ADDR, listen address of the /metrics endpoint
+}
+
+// Settings converts the config into the telemetry metrics settings.
+// Converte a configuração nas configurações de métricas do telemetry.
+func (c MetricsConfig) Settings() telemetry.MetricsSettings {
+	return telemetry.MetricsSettings{Exporters: c.Exporters, PrometheusAddr: c.PrometheusAddr}
+}
+
+// Validate checks the exporter names.
+// Valida os nomes dos exportadores.
+func (c MetricsConfig) Validate(check func(bool, string, ...any)) {
+	for _, exporter := range c.Exporters {
+		switch exporter {
+		case "otlp":
+		case "none":
+			check(len(c.Exporters) == 1, "metrics exporter none cannot be combined with other exporters")
+		case "prometheus":
+			check(c.PrometheusAddr != "", "metrics exporter prometheus needs PROMETHEUS_ADDR")
+		default:
+			check(false, "unknown metrics exporter %q (available: otlp, prometheus, none)", exporter)
+		}
+	}
+}
+
+// LogConfig configures the structured logger.
+// Configura o logger estruturado.
+type LogConfig struct {
+	Level     string   `yaml:"level"`     // LOG_LEVEL ("debug", "info", "warn" or "error")
+	Exporters []string `yaml:"exporters"` // OTEL_LOGS_EXPORTER ("otlp" or "none")
+}
+
+// Settings converts the config into the telemetry log settings.
+// Converte a configuração nas configurações de log do telemetry.
+func (c LogConfig) Settings() telemetry.LogSettings {
+	return telemetry.LogSettings{Level: c.Level, Exporters: c.Exporters}
+}
+
+// Validate checks the level and the exporter names.
+// Valida o nível e os nomes dos exportadores.
+func (c LogConfig) Validate(check func(bool, string, ...any)) {
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Level)) == nil, "unknown log level %q (available: debug, info, warn, error)", c.Level)
+	for _, exporter := range c.Exporters {
+		switch exporter {
+		case "otlp":
+		case "none":
+			check(len(c.Exporters) == 1, "logs exporter none cannot be combined with other exporters")
+		default:
+			check(false, "unknown logs exporter %q (available: otlp, none)", exporter)
+		}
+	}
+}
+
+// IsHTTPURL tells whether raw is an absolute http or https URL.
+// Informa se raw é uma URL http ou https absoluta.
+func IsHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
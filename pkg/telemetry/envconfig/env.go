@@ -0,0 +1,289 @@
+// Package envconfig holds the configuration shared by the services: the telemetry
+// settings and the loader of environment variables.
+// O pacote envconfig guarda a configuração compartilhada pelos serviços: as
+// configurações de telemetria e o leitor de variáveis de ambiente.
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is a sensitive value such as an API key or a password.
+// It can be set inline or read from a file, e.g. a Docker secret under /run/secrets.
+// Secret é um valor sensível, como uma chave de API ou uma senha.
+// Pode ser definido diretamente ou lido de um arquivo, ex.: um Docker secret em /run/secrets.
+type Secret string
+
+// String hides the value so secrets never end up in logs.
+// Esconde o valor para que segredos nunca parem nos logs.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// UnmarshalYAML accepts either an inline value or a mapping with a file path:
+//
+//	api_key: "inline-value"
+//	api_key: {file: /run/secrets/weather_api_key}
+//
+// Aceita um valor direto ou um mapeamento com o caminho de um arquivo.
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Secret(node.Value)
+		return nil
+	}
+	var ref struct {
+		File string `yaml:"file"`
+	}
+	if err := node.Decode(&ref); err != nil {
+		return err
+	}
+	value, err := readSecretFile(ref.File)
+	if err != nil {
+		return err
+	}
+	*s = value
+	return nil
+}
+
+// readSecretFile reads a secret from a file, trimming the trailing newline.
+// Lê um segredo de um arquivo, removendo a quebra de linha final.
+func readSecretFile(path string) (Secret, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret file: %w", err)
+	}
+	return Secret(strings.TrimRight(string(content), "\r\n")), nil
+}
+
+// Env overlays environment variables on the configuration, collecting
+// every parse error instead of stopping at the first one.
+// Sobrepõe variáveis de ambiente na configuração, acumulando todos os erros
+// de leitura em vez de parar no primeiro.
+type Env struct {
+	lookup func(string) (string, bool) // Usually os.LookupEnv
+	errs   []error                     // Parse errors found so far
+}
+
+// NewEnv reads the variables through lookup, usually os.LookupEnv.
+// Lê as variáveis por meio de lookup, normalmente os.LookupEnv.
+func NewEnv(lookup func(string) (string, bool)) *Env {
+	return &Env{lookup: lookup}
+}
+
+// Err joins every parse error found so far.
+// Junta todos os erros de leitura encontrados até agora.
+func (e *Env) Err() error {
+	return errors.Join(e.errs...)
+}
+
+// Value returns the trimmed value of a variable, ok is false when unset or empty.
+// Retorna o valor de uma variável sem espaços, ok é falso quando não definida ou vazia.
+func (e *Env) Value(name string) (string, bool) {
+	value, ok := e.lookup(name)
+	value = strings.TrimSpace(value)
+	return value, ok && value != ""
+}
+
+// Fail records a parse error for a variable.
+// Registra um erro de leitura de uma variável.
+func (e *Env) Fail(name string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("invalid %s: %w", name, err))
+}
+
+// String reads a plain value.
+// Lê um valor simples.
+func (e *Env) String(name string, dst *string) {
+	if value, ok := e.Value(name); ok {
+		*dst = value
+	}
+}
+
+// Bool reads a boolean such as "true" or "0".
+// Lê um booleano como "true" ou "0".
+func (e *Env) Bool(name string, dst *bool) {
+	value, ok := e.Value(name)
+	if !ok {
+		return
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		e.Fail(name, err)
+		return
+	}
+	*dst = enabled
+}
+
+// Int reads an integer.
+// Lê um inteiro.
+func (e *Env) Int(name string, dst *int) {
+	value, ok := e.Value(name)
+	if !ok {
+		return
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		e.Fail(name, err)
+		return
+	}
+	*dst = number
+}
+
+// Float reads a decimal number.
+// Lê um número decimal.
+func (e *Env) Float(name string, dst *float64) {
+	value, ok := e.Value(name)
+	if !ok {
+		return
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.Fail(name, err)
+		return
+	}
+	*dst = number
+}
+
+// Duration reads a duration such as "500ms" or "2m".
+// Lê uma duração como "500ms" ou "2m".
+func (e *Env) Duration(name string, dst *time.Duration) {
+	value, ok := e.Value(name)
+	if !ok {
+		return
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		e.Fail(name, err)
+		return
+	}
+	*dst = duration
+}
+
+// List reads a comma separated list, dropping empty entries.
+// Lê uma lista separada por vírgulas, descartando entradas vazias.
+func (e *Env) List(name string, dst *[]string) {
+	value, ok := e.Value(name)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// IntList reads a comma separated list of integers.
+// Lê uma lista de inteiros separada por vírgulas.
+func (e *Env) IntList(name string, dst *[]int) {
+	var items []string
+	e.List(name, &items)
+	if items == nil {
+		return
+	}
+	numbers := make([]int, 0, len(items))
+	for _, item := range items {
+		number, err := strconv.Atoi(item)
+		if err != nil {
+			e.Fail(name, err)
+			return
+		}
+		numbers = append(numbers, number)
+	}
+	*dst = numbers
+}
+
+// IntMap reads a "host=number,..." list.
+// Lê uma lista "host=número,...".
+func (e *Env) IntMap(name string, dst *map[string]int) {
+	var items []string
+	e.List(name, &items)
+	if items == nil {
+		return
+	}
+	numbers := make(map[string]int, len(items))
+	for _, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil {
+			e.Fail(name, fmt.Errorf("entry %q, expected host=number", item))
+			return
+		}
+		numbers[strings.ToLower(strings.TrimSpace(key))] = number
+	}
+	*dst = numbers
+}
+
+// StringMap reads a "name=value,..." list.
+// Lê uma lista "nome=valor,...".
+func (e *Env) StringMap(name string, dst *map[string]string) {
+	var items []string
+	e.List(name, &items)
+	if items == nil {
+		return
+	}
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			e.Fail(name, fmt.Errorf("entry %q, expected name=value", item))
+			return
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	*dst = values
+}
+
+// Attributes reads a "key=value,..." list with percent-encoded values, the
+// OTEL_RESOURCE_ATTRIBUTES format.
+// Lê uma lista "chave=valor,..." com valores codificados em percent, o formato de
+// OTEL_RESOURCE_ATTRIBUTES.
+func (e *Env) Attributes(name string, dst *map[string]string) {
+	var values map[string]string
+	e.StringMap(name, &values)
+	for key, value := range values {
+		decoded, err := url.PathUnescape(value)
+		if err != nil {
+			e.Fail(name, fmt.Errorf("attribute %q: %w", key, err))
+			return
+		}
+		values[key] = decoded
+	}
+	if values != nil {
+		*dst = values
+	}
+}
+
+// Secret reads NAME, or the file named by NAME_FILE (Docker secrets).
+// Setting both is rejected as it is most likely a mistake.
+// Lê NAME, ou o arquivo indicado por NAME_FILE (Docker secrets).
+// Definir ambas é rejeitado, pois provavelmente é um engano.
+func (e *Env) Secret(name string, dst *Secret) {
+	value, inline := e.Value(name)
+	path, fromFile := e.Value(name + "_FILE")
+	switch {
+	case inline && fromFile:
+		e.Fail(name, fmt.Errorf("set either %s or %s_FILE, not both", name, name))
+	case fromFile:
+		secret, err := readSecretFile(path)
+		if err != nil {
+			e.Fail(name+"_FILE", err)
+			return
+		}
+		*dst = secret
+	case inline:
+		*dst = Secret(value)
+	}
+}
@@ -0,0 +1,47 @@
+package envconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvReportsEveryInvalidVariable(t *testing.T) {
+	vars := map[string]string{
+		"OTEL_SERVICE_NAME":        " service-a ",
+		"SHUTDOWN_TIMEOUT":         "soon",
+		"OTLP_INSECURE":            "maybe",
+		"OTEL_RESOURCE_ATTRIBUTES": "team=core,region=sa%2Deast",
+	}
+	env := NewEnv(func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	})
+
+	var (
+		name       string
+		timeout    = 10 * time.Second
+		insecure   = true
+		attributes map[string]string
+	)
+	env.String("OTEL_SERVICE_NAME", &name)
+	env.Duration("SHUTDOWN_TIMEOUT", &timeout)
+	env.Bool("OTLP_INSECURE", &insecure)
+	env.Attributes("OTEL_RESOURCE_ATTRIBUTES", &attributes)
+
+	if name != "service-a" {
+		t.Errorf("name = %q, want the trimmed value", name)
+	}
+	if timeout != 10*time.Second || !insecure {
+		t.Errorf("invalid values must keep the defaults, got %s and %v", timeout, insecure)
+	}
+	if attributes["region"] != "sa-east" {
+		t.Errorf("attributes = %v, want decoded values", attributes)
+	}
+	err := env.Err()
+	for _, variable := range []string{"SHUTDOWN_TIMEOUT", "OTLP_INSECURE"} {
+		if err == nil || !strings.Contains(err.Error(), variable) {
+			t.Errorf("error %v does not report %s", err, variable)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+// ExporterSettings selects where spans are sent.
+// ExporterSettings seleciona para onde os spans são enviados.
+type ExporterSettings struct {
+	Exporters        []string // "otlp", "otlphttp", "zipkin", "console", "file" or "none", several may be combined
+	OTLPHTTPEndpoint string   // Full URL of the OTLP/HTTP traces endpoint, e.g. http://otel-collector:4318/v1/traces
+	ZipkinEndpoint   string   // Full URL of the Zipkin spans endpoint, e.g. http://zipkin:9411/api/v2/spans
+	FilePath         string   // File the "file" exporter appends JSON lines to
+}
+
+// usesOTLP tells whether any signal needs the gRPC connection to the collector.
+// Informa se algum sinal precisa da conexão gRPC com o collector.
+func usesOTLP(exporters ...[]string) bool {
+	for _, names := range exporters {
+		if slices.Contains(names, "otlp") {
+			return true
+		}
+	}
+	return false
+}
+
+// newSpanProcessor builds one batch processor per selected exporter, so a slow
+// destination does not hold the others back. An exporter that cannot be built is
+// skipped with a warning; nil is returned when none is left.
+// Constrói um batch processor por exportador selecionado, para que um destino lento
+// não atrase os demais. Um exportador que não pode ser construído é ignorado com um
+// aviso; nil é retornado quando nenhum resta.
+func (t *Telemetry) newSpanProcessor(settings ExporterSettings, connection ConnectionSettings) sdktrace.SpanProcessor {
+	var processors spanProcessors
+	for _, name := range settings.Exporters {
+		exporter, err := newSpanExporter(name, settings, connection, t.conn)
+		if err != nil {
+			t.recordError(err)
+			slog.Warn("trace exporter disabled", "exporter", name, "error", err)
+			continue
+		}
+		if exporter != nil {
+			processors = append(processors, sdktrace.NewBatchSpanProcessor(exporter))
+		}
+	}
+	switch len(processors) {
+	case 0:
+		return nil
+	case 1:
+		return processors[0]
+	}
+	return processors
+}
+
+// newSpanExporter creates the exporter with the given name, nil for "none".
+// Cria o exportador com o nome informado, nil para "none".
+func newSpanExporter(name string, settings ExporterSettings, connection ConnectionSettings, conn *grpc.ClientConn) (sdktrace.SpanExporter, error) {
+	ctx := context.Background()
+	switch name {
+	case "otlp":
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
+	case "otlphttp":
+		client, err := connection.httpClient(settings.OTLPHTTPEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to secure the OTLP/HTTP connection: %w", err)
+		}
+		return otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(settings.OTLPHTTPEndpoint),
+			otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
+			otlptracehttp.WithHTTPClient(client))
+	case "zipkin":
+		return zipkin.New(settings.ZipkinEndpoint)
+	case "console":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		// Without pretty printing stdouttrace writes one JSON document per span and line
+		// Sem pretty print o stdouttrace escreve um documento JSON por span e linha
+		file, err := os.OpenFile(settings.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open traces file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return fileExporter{exporter, file}, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", name)
+}
+
+// fileExporter closes its file once the exporter is shut down.
+// Fecha o seu arquivo quando o exportador é encerrado.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.Exporter.Shutdown(ctx), e.file.Close())
+}
+
+// spanProcessors fans every span out to several processors.
+// Distribui cada span entre vários processors.
+type spanProcessors []sdktrace.SpanProcessor
+
+func (p spanProcessors) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	for _, processor := range p {
+		processor.OnStart(parent, span)
+	}
+}
+
+func (p spanProcessors) OnEnd(span sdktrace.ReadOnlySpan) {
+	for _, processor := range p {
+		processor.OnEnd(span)
+	}
+}
+
+func (p spanProcessors) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p {
+		errs = append(errs, processor.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p spanProcessors) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
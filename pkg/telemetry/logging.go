@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogSettings configures the service logger.
+// LogSettings configura o logger do serviço.
+type LogSettings struct {
+	Level     string   // "debug", "info", "warn" or "error"
+	Exporters []string // "otlp" additionally ships records to the collector, "none" keeps them on stdout only
+}
+
+// setupLogging installs a JSON slog logger on stdout as the default logger, so the
+// standard log package goes through it too. Records carry the trace_id and span_id
+// of the context they are logged with and, when the OTLP exporter is selected, are
+// also sent to the global LoggerProvider set up by New.
+// Instala um logger slog JSON no stdout como logger padrão, para que o pacote log
+// padrão também passe por ele. Os registros levam o trace_id e o span_id do contexto
+// com que são emitidos e, quando o exportador OTLP é selecionado, também são enviados
+// ao LoggerProvider global configurado por New.
+func setupLogging(serviceName string, settings LogSettings) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(settings.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", settings.Level, err)
+	}
+
+	handlers := []slog.Handler{
+		traceHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})},
+	}
+	for _, exporter := range settings.Exporters {
+		if exporter == "otlp" {
+			// The bridge reads the trace context itself; the global LoggerProvider
+			// delegates to the SDK provider once New sets it
+			// A ponte lê o contexto do trace sozinha; o LoggerProvider global
+			// delega ao provider do SDK assim que New o define
+			handlers = append(handlers, leveledHandler{otelslog.NewHandler(serviceName), level})
+		}
+	}
+
+	logger := slog.New(handlers[0])
+	if len(handlers) > 1 {
+		logger = slog.New(fanoutHandler(handlers))
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// traceHandler adds the trace_id and span_id of the record context.
+// Adiciona o trace_id e o span_id do contexto do registro.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+// leveledHandler applies the minimum level to a handler that has none of its own.
+// Aplica o nível mínimo a um handler que não tem um próprio.
+type leveledHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h leveledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h leveledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return leveledHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h leveledHandler) WithGroup(name string) slog.Handler {
+	return leveledHandler{h.Handler.WithGroup(name), h.level}
+}
+
+// fanoutHandler sends every record to all of its handlers.
+// Envia cada registro para todos os seus handlers.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
+
+// RequestLogger logs one structured line per HTTP request, replacing chi's text logger.
+// Requests ending in a server error are logged as errors, the rest at debug level
+// for probes and info for everything else.
+// Registra uma linha estruturada por requisição HTTP, substituindo o logger texto do chi.
+// Requisições que terminam em erro do servidor são registradas como erro, as demais
+// em nível debug para probes e info para todo o resto.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case strings.HasPrefix(r.URL.Path, "/health"):
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "http request",
+			"http.request.method", r.Method,
+			"url.path", r.URL.Path,
+			"http.response.status_code", recorder.status,
+			"client.address", r.RemoteAddr,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// statusRecorder remembers the status code written by a handler.
+// Guarda o código de status escrito por um handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// HTTPMetrics records the RED metrics of the HTTP server: request rate and errors
+// through the duration histogram count and its status code, plus the duration itself,
+// following the OpenTelemetry HTTP semantic conventions.
+// Registra as métricas RED do servidor HTTP: taxa de requisições e erros pela
+// contagem do histograma de duração e seu status, além da própria duração,
+// seguindo as convenções semânticas HTTP do OpenTelemetry.
+func HTTPMetrics(next http.Handler) http.Handler {
+	meter := otel.Meter("http-server")
+	duration, _ := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10))
+	active, _ := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of HTTP server requests in flight"))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := attribute.String("http.request.method", r.Method)
+		active.Add(r.Context(), 1, metric.WithAttributes(method))
+		defer active.Add(r.Context(), -1, metric.WithAttributes(method))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		attrs := []attribute.KeyValue{
+			method,
+			attribute.Int("http.response.status_code", recorder.status),
+		}
+		// The route is only known once chi has matched the request, unmatched paths are
+		// left out so they cannot blow up the cardinality
+		// A rota só é conhecida depois que o chi casou a requisição, caminhos sem rota
+		// ficam de fora para não explodir a cardinalidade
+		if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+			attrs = append(attrs, attribute.String("http.route", routeCtx.RoutePattern()))
+		}
+		if recorder.status >= http.StatusInternalServerError {
+			attrs = append(attrs, attribute.String("error.type", strconv.Itoa(recorder.status)))
+		}
+		duration.Record(r.Context(), time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	})
+}
@@ -0,0 +1,83 @@
+// Package telemetry sets up the traces, metrics and logs shared by the services:
+// providers and shutdown, the HTTP server middleware and client transport, and the
+// helpers used by the handlers.
+// O pacote telemetry configura os traces, métricas e logs compartilhados pelos
+// serviços: providers e encerramento, o middleware do servidor HTTP e o transporte
+// do cliente, e os helpers usados pelos handlers.
+package telemetry
+
+// Option changes one part of the configuration used by New.
+// Option altera uma parte da configuração usada por New.
+type Option func(*config)
+
+// config gathers the settings of every signal, starting from defaultConfig.
+// Reúne as configurações de todos os sinais, partindo de defaultConfig.
+type config struct {
+	resource   ResourceSettings
+	collector  string // host:port of the collector OTLP gRPC receiver
+	connection ConnectionSettings
+	tracing    TracingSettings
+	metrics    MetricsSettings
+	logs       LogSettings
+}
+
+// defaultConfig exports traces and metrics over plaintext OTLP gRPC to a local
+// collector, samples like the SDK default and logs at info level on stdout.
+// Exporta traces e métricas via OTLP gRPC sem TLS para um collector local, amostra
+// como o padrão do SDK e registra logs em nível info no stdout.
+func defaultConfig() config {
+	return config{
+		collector:  "localhost:4317",
+		connection: ConnectionSettings{Insecure: true},
+		tracing: TracingSettings{
+			Sampler:      "parentbased_always_on",
+			TailSampling: DefaultTailSamplingSettings(),
+			Export:       ExporterSettings{Exporters: []string{"otlp"}},
+		},
+		metrics: MetricsSettings{Exporters: []string{"otlp"}},
+		logs:    LogSettings{Level: "info"},
+	}
+}
+
+// WithResource describes the service instance behind the telemetry.
+// Descreve a instância do serviço por trás da telemetria.
+func WithResource(settings ResourceSettings) Option {
+	return func(c *config) {
+		c.resource = settings
+	}
+}
+
+// WithCollector sets the collector address, e.g. "otel-collector:4317", and how the
+// connection to it is secured.
+// Define o endereço do collector, ex.: "otel-collector:4317", e como a conexão com ele
+// é protegida.
+func WithCollector(endpoint string, connection ConnectionSettings) Option {
+	return func(c *config) {
+		c.collector = endpoint
+		c.connection = connection
+	}
+}
+
+// WithTracing sets how traces are sampled, exported and propagated.
+// Define como os traces são amostrados, exportados e propagados.
+func WithTracing(settings TracingSettings) Option {
+	return func(c *config) {
+		c.tracing = settings
+	}
+}
+
+// WithMetrics selects where metrics are sent.
+// Seleciona para onde as métricas são enviadas.
+func WithMetrics(settings MetricsSettings) Option {
+	return func(c *config) {
+		c.metrics = settings
+	}
+}
+
+// WithLogs sets the log level and where records are sent.
+// Define o nível de log e para onde os registros são enviados.
+func WithLogs(settings LogSettings) Option {
+	return func(c *config) {
+		c.logs = settings
+	}
+}
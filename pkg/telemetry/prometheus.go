@@ -0,0 +1,65 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// MetricsSettings selects where metrics are sent.
+// MetricsSettings seleciona para onde as métricas são enviadas.
+type MetricsSettings struct {
+	Exporters      []string // "otlp", "prometheus" or "none", several may be combined
+	PrometheusAddr string   // Listen address of the /metrics endpoint, kept apart from the public router
+}
+
+// newPrometheusReader creates a metric reader exposed on its own /metrics server.
+// The address is bound before returning, so a port already in use is reported as an
+// error instead of a server that silently never starts.
+// Besides the OpenTelemetry instruments the registry carries the process collector;
+// Go runtime metrics come from the runtime instrumentation shared with the OTLP path.
+// Cria um leitor de métricas exposto em seu próprio servidor /metrics.
+// O endereço é vinculado antes do retorno, para que uma porta já em uso seja reportada
+// como erro em vez de um servidor que nunca sobe sem avisar.
+// Além dos instrumentos OpenTelemetry o registro traz o coletor de processo;
+// as métricas do runtime Go vêm da instrumentação de runtime compartilhada com o caminho OTLP.
+func newPrometheusReader(addr string) (sdkmetric.Reader, *http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen for the prometheus metrics endpoint: %w", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		listener.Close()
+		return nil, nil, fmt.Errorf("failed to register process collector: %w", err)
+	}
+
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		listener.Close()
+		return nil, nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("prometheus metrics endpoint stopped", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("prometheus metrics endpoint listening", "addr", server.Addr)
+
+	return exporter, server, nil
+}
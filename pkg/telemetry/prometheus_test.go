@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+func TestPrometheusReaderServesMetrics(t *testing.T) {
+	reader, server, err := newPrometheusReader("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown(context.Background())
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	// The address is bound on return, the request needs no retry
+	resp, err := http.Get("http://" + server.Addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "process_") {
+		t.Errorf("GET /metrics = %d, body without the process collector:\n%s", resp.StatusCode, body)
+	}
+}
+
+func TestPrometheusReaderReportsABusyAddress(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	if _, _, err := newPrometheusReader(busy.Addr().String()); err == nil {
+		t.Error("newPrometheusReader on a busy address should fail")
+	}
+}
@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// NewPropagator builds a composite propagator from OTEL_PROPAGATORS names:
+// "tracecontext", "baggage", "b3" (single header), "b3multi" and "jaeger".
+// "none" alone disables propagation and no names means "tracecontext,baggage".
+// Extraction tries every format, injection writes all of them.
+// Constrói um propagador composto a partir dos nomes de OTEL_PROPAGATORS:
+// "tracecontext", "baggage", "b3" (cabeçalho único), "b3multi" e "jaeger".
+// "none" sozinho desativa a propagação e nenhum nome equivale a "tracecontext,baggage".
+// A extração tenta todos os formatos e a injeção escreve todos eles.
+func NewPropagator(names []string) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		names = []string{"tracecontext", "baggage"}
+	}
+	var propagators []propagation.TextMapPropagator
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none":
+		default:
+			return nil, fmt.Errorf("unknown propagator %q, expected tracecontext, baggage, b3, b3multi, jaeger or none", name)
+		}
+	}
+	if seen["none"] && len(seen) > 1 {
+		return nil, fmt.Errorf("propagator \"none\" cannot be combined with others")
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+// traceContextFields are the trace context headers of every format NewPropagator
+// supports, configured or not.
+// Cabeçalhos de contexto de trace de todos os formatos suportados por NewPropagator,
+// configurados ou não.
+var traceContextFields = func() []string {
+	propagator, _ := NewPropagator([]string{"tracecontext", "b3", "b3multi", "jaeger"})
+	return propagator.Fields()
+}()
+
+// RemovePropagationFields deletes from header the trace context of every supported
+// format and the fields of the global propagator. Call it on headers copied from an
+// incoming request before sending them on: the client transport only overwrites the
+// formats it injects, and a stale header of another format (e.g. a gateway's b3)
+// would win the extraction downstream.
+// Remove de header o contexto de trace de todos os formatos suportados e os campos
+// do propagador global. Use em cabeçalhos copiados de uma requisição recebida antes
+// de reenviá-los: o transporte do cliente só sobrescreve os formatos que injeta, e
+// um cabeçalho antigo de outro formato (ex.: o b3 de um gateway) venceria a extração
+// adiante.
+func RemovePropagationFields(header http.Header) {
+	for _, field := range append(otel.GetTextMapPropagator().Fields(), traceContextFields...) {
+		header.Del(field)
+	}
+}
+
+// BaggageAttributes returns the context baggage members selected in
+// TracingSettings.BaggageAttributes as span attributes under the same key, e.g.
+// "tenant.id". Missing members are skipped.
+// Retorna os membros do baggage do contexto selecionados em
+// TracingSettings.BaggageAttributes como atributos de span com a mesma chave, ex.:
+// "tenant.id". Membros ausentes são ignorados.
+func (t *Telemetry) BaggageAttributes(ctx context.Context) []attribute.KeyValue {
+	return baggageAttributes(ctx, t.baggageKeys)
+}
+
+// baggageAttributes returns the members of the context baggage named in keys.
+// Retorna os membros do baggage do contexto listados em keys.
+func baggageAttributes(ctx context.Context, keys []string) []attribute.KeyValue {
+	if len(keys) == 0 {
+		return nil
+	}
+	bag := baggage.FromContext(ctx)
+	var attrs []attribute.KeyValue
+	for _, key := range keys {
+		if member := bag.Member(key); member.Key() != "" {
+			attrs = append(attrs, attribute.String(key, member.Value()))
+		}
+	}
+	return attrs
+}
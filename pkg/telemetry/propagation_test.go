@@ -0,0 +1,29 @@
+package telemetry
+
+import (
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestRemovePropagationFields(t *testing.T) {
+	propagator, err := NewPropagator([]string{"b3multi", "baggage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagator)
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	header := http.Header{}
+	for _, name := range []string{"traceparent", "tracestate", "b3", "X-B3-TraceId", "X-B3-SpanId", "X-B3-Sampled", "uber-trace-id", "baggage", "X-Request-Id"} {
+		header.Set(name, "stale")
+	}
+
+	RemovePropagationFields(header)
+
+	if len(header) != 1 || header.Get("X-Request-Id") == "" {
+		t.Errorf("only X-Request-Id should be left, got %v", header)
+	}
+}
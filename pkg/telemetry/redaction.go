@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// redactedURLKeys are the span attributes holding a full request URL: the current
+// semantic convention and the one emitted with OTEL_SEMCONV_STABILITY_OPT_IN=http/dup.
+// Atributos de span que guardam a URL completa da requisição: a convenção semântica
+// atual e a emitida com OTEL_SEMCONV_STABILITY_OPT_IN=http/dup.
+var redactedURLKeys = []attribute.Key{semconv.URLFullKey, "http.url"}
+
+// urlRedactor hands the spans to next with every query value of the URL attributes
+// replaced by "REDACTED", so API keys sent in the query string, e.g. WeatherAPI's
+// key= or OpenWeatherMap's appid=, never reach an exporter. It works on ended spans
+// because instrumentations such as otelhttp set url.full after the span starts.
+// Entrega os spans a next com todo valor da query dos atributos de URL substituído
+// por "REDACTED", para que chaves de API enviadas na query string, ex.: key= da
+// WeatherAPI ou appid= da OpenWeatherMap, nunca cheguem a um exportador. Atua nos
+// spans encerrados porque instrumentações como o otelhttp definem url.full depois que
+// o span começa.
+type urlRedactor struct {
+	sdktrace.SpanProcessor
+}
+
+func (r urlRedactor) OnEnd(span sdktrace.ReadOnlySpan) {
+	r.SpanProcessor.OnEnd(redactSpan(span))
+}
+
+// redactSpan returns span itself when none of its URL attributes carries a query.
+// Retorna o próprio span quando nenhum de seus atributos de URL traz uma query.
+func redactSpan(span sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	attrs := span.Attributes()
+	var redacted []attribute.KeyValue
+	for i, attr := range attrs {
+		if !slices.Contains(redactedURLKeys, attr.Key) {
+			continue
+		}
+		value, ok := redactQuery(attr.Value.AsString())
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = slices.Clone(attrs)
+		}
+		redacted[i] = attribute.String(string(attr.Key), value)
+	}
+	if redacted == nil {
+		return span
+	}
+	return redactedSpan{span, redacted}
+}
+
+// redactedSpan overrides the attributes of an ended span.
+// Substitui os atributos de um span encerrado.
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs []attribute.KeyValue
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+// redactQuery keeps the query keys of raw and replaces their values, reporting
+// whether anything changed. An unparsable URL loses its whole query.
+// Mantém as chaves da query de raw e substitui seus valores, informando se algo
+// mudou. Uma URL que não pode ser lida perde a query inteira.
+func redactQuery(raw string) (string, bool) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		if before, _, found := strings.Cut(raw, "?"); found {
+			return before + "?REDACTED", true
+		}
+		return raw, false
+	}
+	if parsed.RawQuery == "" {
+		return raw, false
+	}
+	query := parsed.Query()
+	for _, values := range query {
+		for i := range values {
+			values[i] = "REDACTED"
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), true
+}
@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestNewTransportRedactsQueryValues(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(urlRedactor{recorder}),
+	)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	resp, err := client.Get(server.URL + "/current.json?key=secret-key&q=S%C3%A3o+Paulo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	var full string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == semconv.URLFullKey {
+			full = attr.Value.AsString()
+		}
+	}
+	if want := server.URL + "/current.json?key=REDACTED&q=REDACTED"; full != want {
+		t.Errorf("url.full = %q, want %q", full, want)
+	}
+	if strings.Contains(full, "secret-key") {
+		t.Errorf("url.full leaks the API key: %q", full)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		raw, want string
+		changed   bool
+	}{
+		{"https://api.openweathermap.org/data/2.5/weather?q=Recife&appid=abc", "https://api.openweathermap.org/data/2.5/weather?appid=REDACTED&q=REDACTED", true},
+		{"https://viacep.com.br/ws/01001000/json", "https://viacep.com.br/ws/01001000/json", false},
+		{"http://[::1:bad/path?key=abc", "http://[::1:bad/path?REDACTED", true},
+	}
+	for _, test := range tests {
+		got, changed := redactQuery(test.raw)
+		if got != test.want || changed != test.changed {
+			t.Errorf("redactQuery(%q) = %q, %v, want %q, %v", test.raw, got, changed, test.want, test.changed)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Version is the service version stamped at build time, e.g.
+//
+//	go build -ldflags "-X telemetry.Version=1.4.0"
+//
+// When empty the version comes from the module build info.
+// Versão do serviço definida no build. Quando vazia a versão vem das informações de
+// build do módulo.
+var Version string
+
+// ResourceSettings describes the service instance behind the telemetry.
+// ResourceSettings descreve a instância do serviço por trás da telemetria.
+type ResourceSettings struct {
+	Version     string            // service.version, empty uses Version or the build info
+	InstanceID  string            // service.instance.id, empty generates a random UUID per process
+	Environment string            // deployment.environment, e.g. "production"
+	Attributes  map[string]string // Extra attributes, usually from OTEL_RESOURCE_ATTRIBUTES
+}
+
+// newResource builds the resource shared by traces, metrics and logs. From the lowest
+// to the highest precedence it holds the defaults (build version and a random instance
+// id), the host, OS, process, container and SDK detectors, settings.Attributes and
+// finally the explicit settings and the service name. Detectors that fail are skipped
+// with a warning.
+// Monta o resource compartilhado por traces, métricas e logs. Da menor para a maior
+// precedência ele traz os padrões (versão do build e um id de instância aleatório), os
+// detectores de host, SO, processo, container e SDK, settings.Attributes e por fim as
+// configurações explícitas e o nome do serviço. Detectores que falham são ignorados com
+// um aviso.
+func newResource(serviceName string, settings ResourceSettings) *resource.Resource {
+	defaults := []attribute.KeyValue{semconv.ServiceInstanceID(uuid.NewString())}
+	if version := buildVersion(); version != "" {
+		defaults = append(defaults, semconv.ServiceVersion(version))
+	}
+
+	extra := make([]attribute.KeyValue, 0, len(settings.Attributes))
+	for key, value := range settings.Attributes {
+		extra = append(extra, attribute.String(key, value))
+	}
+
+	explicit := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if settings.Version != "" {
+		explicit = append(explicit, semconv.ServiceVersion(settings.Version))
+	}
+	if settings.InstanceID != "" {
+		explicit = append(explicit, semconv.ServiceInstanceID(settings.InstanceID))
+	}
+	if settings.Environment != "" {
+		explicit = append(explicit, semconv.DeploymentEnvironment(settings.Environment))
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(defaults...),
+		resource.WithHost(),
+		resource.WithOSType(),
+		// Command line arguments are left out, they may carry secrets
+		// Os argumentos da linha de comando ficam de fora, podem conter segredos
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithContainer(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(extra...),
+		resource.WithAttributes(explicit...),
+	)
+	if err != nil {
+		// resource.New still returns what it could detect
+		// resource.New ainda retorna o que conseguiu detectar
+		slog.Warn("some resource attributes could not be detected", "error", err)
+	}
+	return res
+}
+
+// buildVersion returns Version, else the main module version, else the VCS revision.
+// Retorna Version, senão a versão do módulo principal, senão a revisão do VCS.
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			return setting.Value[:12]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingSettings configures how traces are sampled, exported and propagated.
+// TracingSettings configura como os traces são amostrados, exportados e propagados.
+type TracingSettings struct {
+	Sampler     string // OTEL_TRACES_SAMPLER name, e.g. "parentbased_traceidratio" or "parentbased_ratelimited"
+	SamplerArg  string // OTEL_TRACES_SAMPLER_ARG: the ratio, or traces per second for the rate-limited samplers
+	DebugHeader string // Requests carrying this header are always sampled, empty disables the override
+
+	TailSampling TailSamplingSettings // Decides again once each trace has ended, see TailSampler
+
+	Export ExporterSettings // Where the sampled spans are sent
+
+	Propagators       []string // OTEL_PROPAGATORS names, see NewPropagator
+	BaggageAttributes []string // Baggage members copied into the request span attributes
+}
+
+// NewSampler builds the sampler named like the OTEL_TRACES_SAMPLER values, plus the
+// rate-limited samplers "ratelimited" and "parentbased_ratelimited" whose argument is
+// the number of traces per second. Every sampler honours the debug override set by
+// DebugSampling.
+// Constrói o sampler nomeado como os valores de OTEL_TRACES_SAMPLER, além dos samplers
+// com limite de taxa "ratelimited" e "parentbased_ratelimited" cujo argumento é o
+// número de traces por segundo. Todo sampler respeita o override de debug definido
+// por DebugSampling.
+func NewSampler(name, arg string) (sdktrace.Sampler, error) {
+	var sampler sdktrace.Sampler
+	switch name {
+	case "always_on", "parentbased_always_on":
+		sampler = sdktrace.AlwaysSample()
+	case "always_off", "parentbased_always_off":
+		sampler = sdktrace.NeverSample()
+	case "traceidratio", "parentbased_traceidratio":
+		ratio, err := parseSamplerArg(arg, 1)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid sampler argument %q, expected a ratio in [0, 1]", arg)
+		}
+		sampler = sdktrace.TraceIDRatioBased(ratio)
+	case "ratelimited", "parentbased_ratelimited":
+		perSecond, err := parseSamplerArg(arg, 10)
+		if err != nil || perSecond <= 0 {
+			return nil, fmt.Errorf("invalid sampler argument %q, expected traces per second above 0", arg)
+		}
+		sampler = newRateLimitedSampler(perSecond)
+	default:
+		return nil, fmt.Errorf("unknown sampler %q (available: always_on, always_off, traceidratio, ratelimited and their parentbased_ variants)", name)
+	}
+	if strings.HasPrefix(name, "parentbased_") {
+		sampler = sdktrace.ParentBased(sampler)
+	}
+	return debugSampler{sampler}, nil
+}
+
+// parseSamplerArg parses the sampler argument, using def when empty.
+// Interpreta o argumento do sampler, usando def quando vazio.
+func parseSamplerArg(arg string, def float64) (float64, error) {
+	if arg == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(arg, 64)
+}
+
+// rateLimitedSampler samples at most perSecond traces per second with a token bucket,
+// allowing bursts of up to one second worth of traces.
+// Amostra no máximo perSecond traces por segundo com um token bucket, permitindo
+// rajadas de até um segundo de traces.
+type rateLimitedSampler struct {
+	perSecond float64
+
+	mu     sync.Mutex
+	tokens float64   // Traces that may still be sampled
+	last   time.Time // Last time the bucket was refilled
+}
+
+func newRateLimitedSampler(perSecond float64) *rateLimitedSampler {
+	return &rateLimitedSampler{perSecond: perSecond, tokens: perSecond, last: time.Now()}
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.mu.Lock()
+	now := time.Now()
+	s.tokens = min(s.perSecond, s.tokens+now.Sub(s.last).Seconds()*s.perSecond)
+	s.last = now
+	decision := sdktrace.Drop
+	if s.tokens >= 1 {
+		s.tokens--
+		decision = sdktrace.RecordAndSample
+	}
+	s.mu.Unlock()
+
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.perSecond)
+}
+
+// debugKey marks a context whose traces must be sampled.
+// Marca um contexto cujos traces devem ser amostrados.
+type debugKey struct{}
+
+// DebugSampling marks the requests carrying header so that every span they start
+// is sampled, whatever the configured sampler decides. Values "0" and "false" are ignored.
+// Marca as requisições que trazem header para que todo span iniciado por elas seja
+// amostrado, seja qual for a decisão do sampler configurado. Os valores "0" e "false" são ignorados.
+func DebugSampling(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if header == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if value := r.Header.Get(header); value != "" && value != "0" && !strings.EqualFold(value, "false") {
+				r = r.WithContext(context.WithValue(r.Context(), debugKey{}, true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// debugSampler always samples marked contexts and defers to the wrapped sampler otherwise.
+// Sempre amostra contextos marcados e delega ao sampler encapsulado nos demais casos.
+type debugSampler struct {
+	sdktrace.Sampler
+}
+
+func (s debugSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if debug, _ := p.ParentContext.Value(debugKey{}).(bool); debug {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.Sampler.ShouldSample(p)
+}
+
+func (s debugSampler) Description() string {
+	return "Debug{" + s.Sampler.Description() + "}"
+}
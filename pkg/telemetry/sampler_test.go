@@ -0,0 +1,142 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sample asks sampler about a new span started under ctx.
+func sample(ctx context.Context, sampler sdktrace.Sampler) sdktrace.SamplingDecision {
+	return sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       traceID(1),
+		Name:          "test",
+	}).Decision
+}
+
+// withRemoteParent returns a context holding a remote parent, sampled or not.
+func withRemoteParent(sampled bool) context.Context {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID(1),
+		SpanID:     trace.SpanID{7: 1},
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+// sampled counts how many of n new root spans sampler keeps.
+func sampled(sampler sdktrace.Sampler, n int) int {
+	kept := 0
+	for range n {
+		if sample(context.Background(), sampler) == sdktrace.RecordAndSample {
+			kept++
+		}
+	}
+	return kept
+}
+
+func TestRateLimitedSamplerKeepsThePerSecondLimit(t *testing.T) {
+	sampler := newRateLimitedSampler(5)
+
+	if got := sampled(sampler, 20); got != 5 {
+		t.Fatalf("sampled %d traces out of a burst of 20, want 5", got)
+	}
+
+	// Half a second refills half the bucket
+	sampler.last = sampler.last.Add(-500 * time.Millisecond)
+	if got := sampled(sampler, 20); got != 2 {
+		t.Errorf("sampled %d traces after half a second, want 2", got)
+	}
+
+	// A long pause never grants more than one second worth of traces
+	sampler.last = sampler.last.Add(-time.Minute)
+	if got := sampled(sampler, 20); got != 5 {
+		t.Errorf("sampled %d traces after a minute, want 5", got)
+	}
+}
+
+func TestParentBasedSamplersFollowTheParent(t *testing.T) {
+	for _, name := range []string{"parentbased_always_off", "parentbased_always_on", "parentbased_ratelimited"} {
+		sampler, err := NewSampler(name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sample(withRemoteParent(true), sampler); got != sdktrace.RecordAndSample {
+			t.Errorf("%s with a sampled parent decided %v", name, got)
+		}
+		if got := sample(withRemoteParent(false), sampler); got != sdktrace.Drop {
+			t.Errorf("%s with an unsampled parent decided %v", name, got)
+		}
+	}
+
+	// A sampled parent is followed even once the rate limit is exhausted
+	sampler, err := NewSampler("parentbased_ratelimited", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sampled(sampler, 5); got != 1 {
+		t.Fatalf("sampled %d root traces, want 1", got)
+	}
+	if got := sample(withRemoteParent(true), sampler); got != sdktrace.RecordAndSample {
+		t.Errorf("sampled parent over the limit decided %v", got)
+	}
+}
+
+func TestSamplersWithoutParentBasedIgnoreTheParent(t *testing.T) {
+	sampler, err := NewSampler("always_off", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sample(withRemoteParent(true), sampler); got != sdktrace.Drop {
+		t.Errorf("always_off with a sampled parent decided %v", got)
+	}
+}
+
+func TestDebugHeaderForcesSampling(t *testing.T) {
+	sampler, err := NewSampler("parentbased_always_off", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for value, forced := range map[string]bool{"1": true, "true": true, "yes": true, "": false, "0": false, "FALSE": false} {
+		var decision sdktrace.SamplingDecision
+		handler := DebugSampling("X-Debug-Trace")(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			// Even an unsampled parent is overridden
+			ctx := trace.ContextWithRemoteSpanContext(r.Context(), trace.SpanContextFromContext(withRemoteParent(false)))
+			decision = sample(ctx, sampler)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if value != "" {
+			req.Header.Set("X-Debug-Trace", value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got := decision == sdktrace.RecordAndSample; got != forced {
+			t.Errorf("header %q sampled = %v, want %v", value, got, forced)
+		}
+	}
+}
+
+func TestNewSamplerRejectsInvalidArguments(t *testing.T) {
+	for _, tc := range []struct{ name, arg string }{
+		{"traceidratio", "1.5"},
+		{"parentbased_traceidratio", "half"},
+		{"ratelimited", "0"},
+		{"parentbased_ratelimited", "-1"},
+		{"sometimes", ""},
+	} {
+		if _, err := NewSampler(tc.name, tc.arg); err == nil {
+			t.Errorf("NewSampler(%q, %q) should fail", tc.name, tc.arg)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// RunServer serves HTTP until SIGINT/SIGTERM, then drains in-flight requests
+// within timeout and runs the cleanup functions (e.g. the tracer shutdown) in order,
+// each one with its own timeout so a slow drain does not skip the span flush.
+// Cleanup failures are only logged, the returned error reports the server itself.
+// Serve HTTP até receber SIGINT/SIGTERM, então aguarda as requisições em andamento
+// dentro do timeout e executa as funções de limpeza (ex.: o shutdown do tracer) em ordem,
+// cada uma com seu próprio timeout para que uma drenagem lenta não impeça o envio dos spans.
+// Falhas na limpeza são apenas registradas, o erro retornado se refere ao próprio servidor.
+func RunServer(server *http.Server, timeout time.Duration, cleanups ...func(context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var errs []error
+	select {
+	case err := <-serverErr:
+		// The server never started or died, there is nothing to drain
+		// O servidor não iniciou ou caiu, não há nada para drenar
+		errs = append(errs, fmt.Errorf("server stopped: %w", err))
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining requests", "timeout", timeout.String())
+		stop() // A second signal kills the process right away
+
+		drainCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := server.Shutdown(drainCtx); err != nil {
+			errs = append(errs, fmt.Errorf("error draining requests: %w", err))
+		}
+		cancel()
+	}
+
+	for _, cleanup := range cleanups {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := cleanup(cleanupCtx); err != nil {
+			// Losing telemetry on the way out must not turn a clean stop into a failure
+			// Perder telemetria na saída não deve transformar uma parada limpa em falha
+			slog.Warn("cleanup failed during shutdown", "error", err)
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
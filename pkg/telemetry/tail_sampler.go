@@ -0,0 +1,265 @@
+package telemetry
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TailSamplingSettings configures the in-process tail sampler. Head sampling should
+// keep (at least record) the traces the tail sampler is meant to judge.
+// TailSamplingSettings configura o tail sampler em processo. A amostragem de entrada
+// deve manter (ao menos gravar) os traces que o tail sampler vai avaliar.
+type TailSamplingSettings struct {
+	Enabled          bool          // Disabled sends every span straight to the exporter
+	LatencyThreshold time.Duration // Traces with a span at least this slow are kept
+	HealthyRatio     float64       // Fraction (0..1) of the remaining traces that is kept
+	DecisionWait     time.Duration // Longest a trace is buffered waiting for its local root span
+	MaxTraces        int           // Traces buffered at once, the oldest is decided early beyond it
+	MaxSpansPerTrace int           // Spans buffered per trace, extra spans are dropped
+}
+
+// DefaultTailSamplingSettings returns the settings used when nothing is configured.
+// Retorna as configurações usadas quando nada é configurado.
+func DefaultTailSamplingSettings() TailSamplingSettings {
+	return TailSamplingSettings{
+		LatencyThreshold: time.Second,
+		HealthyRatio:     0.1,
+		DecisionWait:     5 * time.Second,
+		MaxTraces:        10000,
+		MaxSpansPerTrace: 1000,
+	}
+}
+
+// pendingTrace holds the spans of a trace waiting for a decision.
+// Guarda os spans de um trace aguardando uma decisão.
+type pendingTrace struct {
+	id      trace.TraceID
+	spans   []sdktrace.ReadOnlySpan
+	reason  string        // "error" or "latency" once the trace must be kept
+	started time.Time     // When the first span of the trace ended
+	element *list.Element // Position in the arrival order
+}
+
+// TailSampler is a SpanProcessor that buffers the spans of each trace until its local
+// root span ends, then forwards the whole trace to the next processor when it has an
+// error or a slow span, or when it falls in the healthy fraction, and drops it otherwise.
+// Spans ending after the decision follow it. Memory is bounded by MaxTraces and
+// MaxSpansPerTrace.
+// TailSampler é um SpanProcessor que guarda os spans de cada trace até o span raiz local
+// terminar, então encaminha o trace inteiro ao próximo processor quando ele tem um erro
+// ou um span lento, ou quando cai na fração saudável, e o descarta caso contrário.
+// Spans que terminam após a decisão a seguem. A memória é limitada por MaxTraces e
+// MaxSpansPerTrace.
+type TailSampler struct {
+	next     sdktrace.SpanProcessor
+	settings TailSamplingSettings
+	bound    uint64 // Healthy traces whose ID falls below this bound are kept
+
+	mu      sync.Mutex
+	pending map[trace.TraceID]*pendingTrace
+	order   *list.List             // Pending traces by arrival, oldest first
+	decided map[trace.TraceID]bool // Recent decisions, for spans ending late
+	history []trace.TraceID        // Ring of the recent decisions, bounds decided
+	next0   int                    // Next slot in history
+	stop    chan struct{}          // Closed on shutdown to stop the sweeper
+	done    sync.WaitGroup         // Waits for the sweeper
+	traces  metric.Int64Counter    // Decisions, by outcome and reason
+	dropped metric.Int64Counter    // Spans dropped because their trace was full
+}
+
+// NewTailSampler creates a TailSampler in front of next.
+// Cria um TailSampler na frente de next.
+func NewTailSampler(next sdktrace.SpanProcessor, settings TailSamplingSettings) *TailSampler {
+	defaults := DefaultTailSamplingSettings()
+	if settings.DecisionWait <= 0 {
+		settings.DecisionWait = defaults.DecisionWait
+	}
+	if settings.MaxTraces < 1 {
+		settings.MaxTraces = defaults.MaxTraces
+	}
+	if settings.MaxSpansPerTrace < 1 {
+		settings.MaxSpansPerTrace = defaults.MaxSpansPerTrace
+	}
+
+	meter := otel.Meter("tail-sampler")
+	traces, _ := meter.Int64Counter("tail_sampling.traces",
+		metric.WithDescription("Tail sampling decisions, by outcome and reason"))
+	dropped, _ := meter.Int64Counter("tail_sampling.dropped_spans",
+		metric.WithDescription("Spans dropped because their trace reached the per-trace limit"))
+
+	s := &TailSampler{
+		next:     next,
+		settings: settings,
+		// Same rule as TraceIDRatioBased, so every service keeps the same healthy traces
+		// Mesma regra do TraceIDRatioBased, para que todos os serviços mantenham os mesmos traces saudáveis
+		bound:   uint64(max(0, min(1, settings.HealthyRatio)) * (1 << 63)),
+		pending: map[trace.TraceID]*pendingTrace{},
+		order:   list.New(),
+		decided: map[trace.TraceID]bool{},
+		history: make([]trace.TraceID, settings.MaxTraces),
+		stop:    make(chan struct{}),
+		traces:  traces,
+		dropped: dropped,
+	}
+	s.done.Add(1)
+	go s.sweep()
+	return s
+}
+
+func (s *TailSampler) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	s.next.OnStart(parent, span)
+}
+
+func (s *TailSampler) OnEnd(span sdktrace.ReadOnlySpan) {
+	id := span.SpanContext().TraceID()
+
+	s.mu.Lock()
+	if keep, ok := s.decided[id]; ok {
+		s.mu.Unlock()
+		if keep {
+			s.next.OnEnd(span) // Late span of a kept trace
+		}
+		return
+	}
+
+	var ready []*pendingTrace
+	pending, ok := s.pending[id]
+	if !ok {
+		if s.order.Len() >= s.settings.MaxTraces {
+			ready = append(ready, s.take(s.order.Front().Value.(*pendingTrace))) // Make room by deciding the oldest trace
+		}
+		pending = &pendingTrace{id: id, started: time.Now()}
+		pending.element = s.order.PushBack(pending)
+		s.pending[id] = pending
+	}
+	if len(pending.spans) < s.settings.MaxSpansPerTrace {
+		pending.spans = append(pending.spans, span)
+	} else {
+		s.dropped.Add(context.Background(), 1)
+	}
+	switch {
+	case span.Status().Code == codes.Error:
+		pending.reason = "error"
+	case pending.reason == "" && span.EndTime().Sub(span.StartTime()) >= s.settings.LatencyThreshold:
+		pending.reason = "latency"
+	}
+	if parent := span.Parent(); !parent.IsValid() || parent.IsRemote() {
+		ready = append(ready, s.take(pending)) // The local root ended, the trace is complete here
+	}
+	s.mu.Unlock()
+
+	s.forward(ready)
+}
+
+// take removes a trace from the buffer and records the decision for its late spans.
+// Must be called with mu held.
+// Remove um trace do buffer e registra a decisão para os seus spans atrasados.
+// Deve ser chamado com mu travado.
+func (s *TailSampler) take(pending *pendingTrace) *pendingTrace {
+	s.order.Remove(pending.element)
+	delete(s.pending, pending.id)
+
+	if pending.reason == "" {
+		if binary.BigEndian.Uint64(pending.id[8:16])>>1 < s.bound {
+			pending.reason = "healthy"
+		}
+	}
+	if old := s.history[s.next0]; old.IsValid() {
+		delete(s.decided, old)
+	}
+	s.history[s.next0] = pending.id
+	s.next0 = (s.next0 + 1) % len(s.history)
+	s.decided[pending.id] = pending.reason != ""
+	return pending
+}
+
+// forward sends the kept traces to the next processor, outside the lock.
+// Envia os traces mantidos ao próximo processor, fora da trava.
+func (s *TailSampler) forward(traces []*pendingTrace) {
+	for _, pending := range traces {
+		keep := pending.reason != ""
+		reason := pending.reason
+		if !keep {
+			reason = "healthy"
+		}
+		s.traces.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.Bool("sampled", keep),
+			attribute.String("reason", reason),
+		))
+		if !keep {
+			continue
+		}
+		for _, span := range pending.spans {
+			s.next.OnEnd(span)
+		}
+	}
+}
+
+// sweep decides the traces whose local root did not end within DecisionWait,
+// e.g. when it was not sampled or is still running in another goroutine.
+// Decide os traces cujo span raiz local não terminou dentro de DecisionWait,
+// ex.: quando não foi amostrado ou ainda está rodando em outra goroutine.
+func (s *TailSampler) sweep() {
+	defer s.done.Done()
+	ticker := time.NewTicker(s.settings.DecisionWait / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.forward(s.takeOlderThan(time.Now().Add(-s.settings.DecisionWait)))
+		}
+	}
+}
+
+// takeOlderThan takes every pending trace that started before deadline; a zero
+// deadline takes them all.
+// Retira todos os traces pendentes iniciados antes de deadline; um deadline zero
+// retira todos.
+func (s *TailSampler) takeOlderThan(deadline time.Time) []*pendingTrace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ready []*pendingTrace
+	for element := s.order.Front(); element != nil; {
+		pending := element.Value.(*pendingTrace)
+		element = element.Next()
+		if !deadline.IsZero() && pending.started.After(deadline) {
+			break // Arrival order, the rest is newer
+		}
+		ready = append(ready, s.take(pending))
+	}
+	return ready
+}
+
+// ForceFlush decides every pending trace and flushes the next processor.
+// Decide todos os traces pendentes e esvazia o próximo processor.
+func (s *TailSampler) ForceFlush(ctx context.Context) error {
+	s.forward(s.takeOlderThan(time.Time{}))
+	return s.next.ForceFlush(ctx)
+}
+
+// Shutdown decides every pending trace and shuts the next processor down.
+// Decide todos os traces pendentes e encerra o próximo processor.
+func (s *TailSampler) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.stop:
+		return errors.New("tail sampler already shut down")
+	default:
+		close(s.stop)
+	}
+	s.done.Wait()
+	s.forward(s.takeOlderThan(time.Time{}))
+	return s.next.Shutdown(ctx)
+}
@@ -0,0 +1,217 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testSpan describes an ended span for the tail sampler tests.
+type testSpan struct {
+	trace    byte          // Distinguishes the trace, also its healthy sampling key
+	span     byte          // Span ID
+	parent   byte          // Local parent span ID, 0 for a local root
+	remote   bool          // The parent is in another service
+	error    bool          // Ends with an error status
+	duration time.Duration // Defaults to a millisecond
+}
+
+// traceID builds a trace ID whose sampling key (bytes 8 to 15) starts with key.
+func traceID(key byte) trace.TraceID {
+	return trace.TraceID{0: 1, 8: key, 15: 1}
+}
+
+func (s testSpan) readOnly() sdktrace.ReadOnlySpan {
+	duration := s.duration
+	if duration == 0 {
+		duration = time.Millisecond
+	}
+	start := time.Now()
+	stub := tracetest.SpanStub{
+		Name: "test",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID(s.trace),
+			SpanID:     trace.SpanID{7: s.span},
+			TraceFlags: trace.FlagsSampled,
+		}),
+		StartTime: start,
+		EndTime:   start.Add(duration),
+	}
+	if s.parent != 0 || s.remote {
+		stub.Parent = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID(s.trace),
+			SpanID:     trace.SpanID{6: 1, 7: s.parent},
+			TraceFlags: trace.FlagsSampled,
+			Remote:     s.remote,
+		})
+	}
+	if s.error {
+		stub.Status = sdktrace.Status{Code: codes.Error}
+	}
+	return stub.Snapshot()
+}
+
+// newTestTailSampler returns a sampler forwarding to a recorder, shut down with the test.
+func newTestTailSampler(t *testing.T, settings TailSamplingSettings) (*TailSampler, *tracetest.SpanRecorder) {
+	t.Helper()
+	settings.Enabled = true
+	if settings.LatencyThreshold == 0 {
+		settings.LatencyThreshold = time.Second
+	}
+	recorder := tracetest.NewSpanRecorder()
+	sampler := NewTailSampler(recorder, settings)
+	t.Cleanup(func() { sampler.Shutdown(context.Background()) })
+	return sampler, recorder
+}
+
+// forwarded returns the span IDs forwarded for the trace with the given key.
+func forwarded(recorder *tracetest.SpanRecorder, key byte) []byte {
+	var spans []byte
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == traceID(key) {
+			spans = append(spans, span.SpanContext().SpanID()[7])
+		}
+	}
+	return spans
+}
+
+func end(sampler *TailSampler, spans ...testSpan) {
+	for _, span := range spans {
+		sampler.OnEnd(span.readOnly())
+	}
+}
+
+func TestTailSamplerKeepsErrorAndSlowTraces(t *testing.T) {
+	// A zero ratio drops every healthy trace
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0})
+
+	end(sampler,
+		testSpan{trace: 1, span: 2, parent: 1, error: true},
+		testSpan{trace: 1, span: 1},
+		testSpan{trace: 2, span: 2, parent: 1, duration: 2 * time.Second},
+		testSpan{trace: 2, span: 1, remote: true},
+		testSpan{trace: 3, span: 2, parent: 1},
+		testSpan{trace: 3, span: 1},
+	)
+
+	if got := forwarded(recorder, 1); len(got) != 2 {
+		t.Errorf("error trace forwarded spans %v, want both", got)
+	}
+	if got := forwarded(recorder, 2); len(got) != 2 {
+		t.Errorf("slow trace forwarded spans %v, want both", got)
+	}
+	if got := forwarded(recorder, 3); len(got) != 0 {
+		t.Errorf("healthy trace forwarded spans %v, want none", got)
+	}
+}
+
+func TestTailSamplerKeepsTheHealthyRatio(t *testing.T) {
+	// Half the keys: those below 0x80 are kept, like TraceIDRatioBased
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0.5})
+
+	end(sampler, testSpan{trace: 0x10, span: 1}, testSpan{trace: 0x7f, span: 1}, testSpan{trace: 0x80, span: 1}, testSpan{trace: 0xf0, span: 1})
+
+	for key, kept := range map[byte]bool{0x10: true, 0x7f: true, 0x80: false, 0xf0: false} {
+		if got := len(forwarded(recorder, key)) == 1; got != kept {
+			t.Errorf("trace %#x kept = %v, want %v", key, got, kept)
+		}
+	}
+}
+
+func TestTailSamplerDecidesTheOldestTraceWhenFull(t *testing.T) {
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0, MaxTraces: 2})
+
+	// Two pending traces fill the buffer, both without their local root yet
+	end(sampler,
+		testSpan{trace: 1, span: 2, parent: 1, error: true},
+		testSpan{trace: 2, span: 2, parent: 1, error: true},
+	)
+	if got := len(recorder.Ended()); got != 0 {
+		t.Fatalf("%d spans forwarded before any decision", got)
+	}
+
+	// A third trace evicts the oldest, which is decided (and kept) right away
+	end(sampler, testSpan{trace: 3, span: 2, parent: 1})
+
+	if got := forwarded(recorder, 1); len(got) != 1 {
+		t.Errorf("evicted trace forwarded spans %v, want its error span", got)
+	}
+	if got := forwarded(recorder, 2); len(got) != 0 {
+		t.Errorf("trace still pending forwarded spans %v", got)
+	}
+}
+
+func TestTailSamplerDropsSpansBeyondTheTraceLimit(t *testing.T) {
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 1, MaxSpansPerTrace: 2})
+
+	end(sampler,
+		testSpan{trace: 1, span: 2, parent: 1},
+		testSpan{trace: 1, span: 3, parent: 1},
+		testSpan{trace: 1, span: 4, parent: 1},
+		testSpan{trace: 1, span: 1},
+	)
+
+	if got := forwarded(recorder, 1); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("forwarded spans %v, want the first two buffered", got)
+	}
+}
+
+func TestTailSamplerLateSpansFollowTheDecision(t *testing.T) {
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0})
+
+	end(sampler,
+		testSpan{trace: 1, span: 1, error: true},
+		testSpan{trace: 2, span: 1},
+	)
+	// Spans of the same traces ending after their local roots, e.g. async work
+	end(sampler,
+		testSpan{trace: 1, span: 2, parent: 1},
+		testSpan{trace: 2, span: 2, parent: 1, error: true},
+	)
+
+	if got := forwarded(recorder, 1); len(got) != 2 {
+		t.Errorf("kept trace forwarded spans %v, want the late span too", got)
+	}
+	if got := forwarded(recorder, 2); len(got) != 0 {
+		t.Errorf("dropped trace forwarded spans %v, the late error must not revive it", got)
+	}
+}
+
+func TestTailSamplerSweepsTracesWithoutTheirRoot(t *testing.T) {
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0, DecisionWait: 20 * time.Millisecond})
+
+	// The local root never ends here, e.g. it was not sampled
+	end(sampler, testSpan{trace: 1, span: 2, parent: 1, error: true})
+	if got := len(recorder.Ended()); got != 0 {
+		t.Fatalf("%d spans forwarded before the decision wait", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(forwarded(recorder, 1)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := forwarded(recorder, 1); len(got) != 1 {
+		t.Errorf("swept trace forwarded spans %v, want its error span", got)
+	}
+}
+
+func TestTailSamplerShutdownDecidesPendingTraces(t *testing.T) {
+	sampler, recorder := newTestTailSampler(t, TailSamplingSettings{HealthyRatio: 0})
+
+	end(sampler, testSpan{trace: 1, span: 2, parent: 1, error: true})
+	if err := sampler.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := forwarded(recorder, 1); len(got) != 1 {
+		t.Errorf("forwarded spans %v on shutdown, want the pending error span", got)
+	}
+	if err := sampler.Shutdown(context.Background()); err == nil {
+		t.Error("second shutdown should fail")
+	}
+}
@@ -0,0 +1,369 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Telemetry owns the tracer and meter providers and tracks the health of the collector connection.
+// The collector does not need to be up at startup: the connection is established in the
+// background and spans are buffered by the batch processor meanwhile.
+// Telemetry é dona dos providers de traces e métricas e acompanha a saúde da conexão com o collector.
+// O collector não precisa estar no ar na inicialização: a conexão é feita em segundo
+// plano e os spans ficam no buffer do batch processor enquanto isso.
+type Telemetry struct {
+	provider       *sdktrace.TracerProvider // Nil when telemetry fell back to the no-op provider
+	meterProvider  *sdkmetric.MeterProvider // Nil when metrics fell back to the no-op provider
+	conn           *grpc.ClientConn         // Connection to the collector, shared by the OTLP exporters; nil when none is selected
+	metricsServer  *http.Server             // Prometheus /metrics server, nil unless selected
+	loggerProvider *sdklog.LoggerProvider   // Nil unless the OTLP log exporter is selected
+	serviceName    string                   // Names the tracer and meter handed to the service
+	endpoint       string                   // Collector address
+	debugHeader    string                   // Header that forces sampling, see DebugSampling
+	baggageKeys    []string                 // Baggage members copied into the request spans
+	stop           context.CancelFunc       // Stops the connection watcher, nil without a connection
+
+	mu          sync.RWMutex
+	state       connectivity.State // Last known state of the collector connection
+	lastError   error              // Last export or connection error
+	lastErrorAt time.Time          // When lastError happened
+}
+
+// TelemetryHealth is the JSON document served by Telemetry.HealthHandler.
+// TelemetryHealth é o documento JSON servido por Telemetry.HealthHandler.
+type TelemetryHealth struct {
+	Status      string     `json:"status"`                  // "ok", "degraded" or "disabled"
+	Collector   string     `json:"collector"`               // Collector address
+	Connection  string     `json:"connection"`              // gRPC connectivity state, "unused" without OTLP gRPC exporters
+	LastError   string     `json:"last_error,omitempty"`    // Last export or connection error
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"` // When the last error happened
+}
+
+// New sets up the telemetry of serviceName as configured by options. It installs the
+// JSON logger as the default slog logger and describes the service with the resource
+// built from WithResource. It then sets up the global TracerProvider with the sampler
+// and exporters chosen in WithTracing (OTLP over gRPC to the WithCollector endpoint by
+// default). The global MeterProvider gets the exporters chosen in WithMetrics, OTLP
+// over the same connection and/or a Prometheus scrape endpoint. When WithLogs selects
+// OTLP, the global LoggerProvider backs the slog bridge. New never blocks on the
+// collector and never fails: when the pipeline cannot be built a warning is logged and
+// the global no-op provider is kept.
+// Configura a telemetria de serviceName conforme options. Instala o logger JSON como
+// logger padrão do slog e descreve o serviço com o resource montado a partir de
+// WithResource. Depois configura o TracerProvider global com o sampler e os
+// exportadores escolhidos em WithTracing (OTLP via gRPC para o endpoint de
+// WithCollector por padrão). O MeterProvider global recebe os exportadores escolhidos
+// em WithMetrics, OTLP pela mesma conexão e/ou um endpoint de scrape do Prometheus.
+// Quando WithLogs seleciona OTLP, o LoggerProvider global fica por trás da ponte do
+// slog. Nunca bloqueia esperando o collector e nunca falha: quando o pipeline não pode
+// ser montado um aviso é registrado e o provider no-op global é mantido.
+func New(serviceName string, options ...Option) *Telemetry {
+	config := defaultConfig()
+	for _, option := range options {
+		option(&config)
+	}
+	collectorURL, connection := config.collector, config.connection
+	tracing, metrics, logs := config.tracing, config.metrics, config.logs
+
+	// Set up logging first so the warnings below are structured too
+	// Configura os logs primeiro para que os avisos abaixo também sejam estruturados
+	if err := setupLogging(serviceName, logs); err != nil {
+		slog.Warn("invalid log settings, using the default logger", "error", err)
+	}
+
+	t := &Telemetry{
+		serviceName: serviceName,
+		endpoint:    collectorURL,
+		debugHeader: tracing.DebugHeader,
+		baggageKeys: tracing.BaggageAttributes,
+		state:       connectivity.Idle,
+	}
+
+	// The propagator does not depend on the collector, set it even when degraded
+	// O propagador não depende do collector, é configurado mesmo em modo degradado
+	propagator, err := NewPropagator(tracing.Propagators)
+	if err != nil {
+		slog.Warn("invalid propagators, using tracecontext", "error", err)
+		propagator = propagation.TraceContext{}
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	res := newResource(serviceName, config.resource)
+
+	// Only dial the collector when a signal exports over OTLP gRPC. grpc.NewClient does
+	// not dial, the connection is made lazily by the watcher below
+	// Só conecta ao collector quando algum sinal exporta via OTLP gRPC. grpc.NewClient
+	// não conecta, a conexão é feita de forma preguiçosa pelo watcher abaixo
+	if usesOTLP(tracing.Export.Exporters, metrics.Exporters, logs.Exporters) {
+		options, err := connection.grpcOptions(collectorURL)
+		if err != nil {
+			t.disable(fmt.Errorf("failed to secure the collector connection: %w", err))
+			return t
+		}
+		conn, err := grpc.NewClient(collectorURL, options...)
+		if err != nil {
+			t.disable(fmt.Errorf("failed to create gRPC client for collector: %w", err))
+			return t
+		}
+		t.conn = conn
+	}
+
+	sampler, err := NewSampler(tracing.Sampler, tracing.SamplerArg)
+	if err != nil {
+		t.closeConn()
+		t.disable(fmt.Errorf("failed to create sampler: %w", err))
+		return t
+	}
+
+	traceOptions := []sdktrace.TracerProviderOption{sdktrace.WithSampler(sampler), sdktrace.WithResource(res)}
+	if processor := t.newSpanProcessor(tracing.Export, connection); processor != nil {
+		if tracing.TailSampling.Enabled {
+			processor = NewTailSampler(processor, tracing.TailSampling)
+		}
+		processor = urlRedactor{processor} // Query strings may carry API keys
+		traceOptions = append(traceOptions, sdktrace.WithSpanProcessor(processor))
+	}
+	traceProvider := sdktrace.NewTracerProvider(traceOptions...)
+
+	otel.SetTracerProvider(traceProvider)
+
+	// Export failures are reported through the global handler, keep them for the health check
+	// Falhas de exportação são reportadas pelo handler global, guardadas para o health check
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		t.recordError(err)
+		slog.Warn("telemetry export failed", "collector", collectorURL, "error", err)
+	}))
+
+	t.provider = traceProvider
+
+	t.initMeter(res, metrics)
+	t.initLogger(res, logs)
+
+	if t.conn != nil {
+		var ctx context.Context
+		ctx, t.stop = context.WithCancel(context.Background())
+		go t.watch(ctx)
+	}
+
+	return t
+}
+
+// initMeter sets up the MeterProvider with the selected readers and the Go runtime
+// instrumentation. Metrics are optional: on failure tracing still works.
+// Configura o MeterProvider com os leitores selecionados e a instrumentação do runtime Go.
+// Métricas são opcionais: em caso de falha o tracing continua funcionando.
+func (t *Telemetry) initMeter(res *resource.Resource, metrics MetricsSettings) {
+	options := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	for _, exporter := range metrics.Exporters {
+		switch exporter {
+		case "otlp":
+			metricExporter, err := otlpmetricgrpc.New(context.Background(), otlpmetricgrpc.WithGRPCConn(t.conn))
+			if err != nil {
+				t.recordError(err)
+				slog.Warn("OTLP metrics disabled", "collector", t.endpoint, "error", err)
+				continue
+			}
+			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)))
+		case "prometheus":
+			reader, server, err := newPrometheusReader(metrics.PrometheusAddr)
+			if err != nil {
+				t.recordError(err)
+				slog.Warn("prometheus metrics disabled", "addr", metrics.PrometheusAddr, "error", err)
+				continue
+			}
+			t.metricsServer = server
+			options = append(options, sdkmetric.WithReader(reader))
+		}
+	}
+	if len(options) == 1 {
+		return // No reader, keep the no-op MeterProvider
+	}
+
+	t.meterProvider = sdkmetric.NewMeterProvider(options...)
+	otel.SetMeterProvider(t.meterProvider)
+
+	if err := runtime.Start(runtime.WithMeterProvider(t.meterProvider)); err != nil {
+		slog.Warn("Go runtime metrics disabled", "error", err)
+	}
+}
+
+// initLogger sets up the global LoggerProvider when the OTLP log exporter is selected.
+// Configura o LoggerProvider global quando o exportador de logs OTLP é selecionado.
+func (t *Telemetry) initLogger(res *resource.Resource, logs LogSettings) {
+	if !slices.Contains(logs.Exporters, "otlp") {
+		return
+	}
+	logExporter, err := otlploggrpc.New(context.Background(), otlploggrpc.WithGRPCConn(t.conn))
+	if err != nil {
+		t.recordError(err)
+		slog.Warn("OTLP logs disabled", "collector", t.endpoint, "error", err)
+		return
+	}
+	t.loggerProvider = sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)),
+	)
+	global.SetLoggerProvider(t.loggerProvider)
+}
+
+// disable keeps the no-op provider and records why.
+// Mantém o provider no-op e registra o motivo.
+func (t *Telemetry) disable(err error) {
+	t.recordError(err)
+	slog.Warn("telemetry disabled, continuing without tracing", "collector", t.endpoint, "error", err)
+}
+
+// watch connects to the collector in the background and tracks the connection state,
+// logging a warning whenever it becomes unavailable and a notice when it recovers.
+// Conecta ao collector em segundo plano e acompanha o estado da conexão,
+// registrando um aviso quando fica indisponível e uma mensagem quando se recupera.
+func (t *Telemetry) watch(ctx context.Context) {
+	state := t.conn.GetState()
+	for {
+		t.mu.Lock()
+		t.state = state
+		t.mu.Unlock()
+
+		switch state {
+		case connectivity.Idle:
+			t.conn.Connect()
+		case connectivity.Ready:
+			slog.Info("telemetry collector connected", "collector", t.endpoint)
+		case connectivity.TransientFailure:
+			err := fmt.Errorf("collector %s unavailable", t.endpoint)
+			t.recordError(err)
+			slog.Warn("telemetry collector unavailable, buffering spans and retrying", "collector", t.endpoint)
+		case connectivity.Shutdown:
+			return
+		}
+
+		if !t.conn.WaitForStateChange(ctx, state) {
+			return // Telemetry is shutting down
+		}
+		state = t.conn.GetState()
+	}
+}
+
+// recordError keeps the last telemetry error for the health check.
+// Guarda o último erro de telemetria para o health check.
+func (t *Telemetry) recordError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastError = err
+	t.lastErrorAt = time.Now()
+}
+
+// Health reports whether spans are currently reaching the collector.
+// Informa se os spans estão chegando ao collector no momento.
+func (t *Telemetry) Health() TelemetryHealth {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	health := TelemetryHealth{
+		Status:     "ok",
+		Collector:  t.endpoint,
+		Connection: t.state.String(),
+	}
+	if t.conn == nil {
+		health.Connection = "unused"
+	}
+	switch {
+	case t.provider == nil:
+		health.Status = "disabled"
+	case t.conn != nil && t.state != connectivity.Ready:
+		health.Status = "degraded"
+	}
+	if t.lastError != nil {
+		lastErrorAt := t.lastErrorAt
+		health.LastError = t.lastError.Error()
+		health.LastErrorAt = &lastErrorAt
+	}
+	return health
+}
+
+// HealthHandler serves Health as JSON, answering 503 unless the collector is connected.
+// Serve Health como JSON, respondendo 503 a menos que o collector esteja conectado.
+func (t *Telemetry) HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := t.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if health.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	}
+}
+
+// Tracer returns the tracer named after the service, for the handler spans.
+// Retorna o tracer com o nome do serviço, para os spans dos handlers.
+func (t *Telemetry) Tracer() trace.Tracer {
+	return otel.Tracer(t.serviceName)
+}
+
+// Meter returns the meter named after the service, for the business metrics.
+// Retorna o meter com o nome do serviço, para as métricas de negócio.
+func (t *Telemetry) Meter() metric.Meter {
+	return otel.Meter(t.serviceName)
+}
+
+// Shutdown flushes the pending spans, metrics and logs, shuts the providers down and closes the connection.
+// Envia os spans, métricas e logs pendentes, encerra os providers e fecha a conexão.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	if t.stop != nil {
+		t.stop()
+	}
+	var errs []error
+	if err := t.provider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error shutting down tracer provider: %w", err))
+	}
+	if t.metricsServer != nil {
+		if err := t.metricsServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error shutting down metrics endpoint: %w", err))
+		}
+	}
+	if t.meterProvider != nil {
+		if err := t.meterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error shutting down meter provider: %w", err))
+		}
+	}
+	if t.loggerProvider != nil {
+		if err := t.loggerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error shutting down logger provider: %w", err))
+		}
+	}
+	return errors.Join(append(errs, t.closeConn())...)
+}
+
+// closeConn closes the collector connection, if any.
+// Fecha a conexão com o collector, se houver.
+func (t *Telemetry) closeConn() error {
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.Close()
+}
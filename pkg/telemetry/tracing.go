@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/metric/noop"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware instruments the server in the order the pieces depend on each other:
+// DebugSampling with the configured header, HTTPTracing, HTTPMetrics and
+// RequestLogger, the latter two inside the span so logs carry the trace_id.
+// Instrumenta o servidor na ordem em que as partes dependem umas das outras:
+// DebugSampling com o cabeçalho configurado, HTTPTracing, HTTPMetrics e
+// RequestLogger, os dois últimos dentro do span para que os logs tragam o trace_id.
+func (t *Telemetry) Middleware(next http.Handler) http.Handler {
+	return DebugSampling(t.debugHeader)(HTTPTracing(HTTPMetrics(RequestLogger(next))))
+}
+
+// NewTransport wraps base so every outgoing request becomes a client span named
+// after the method and host, e.g. "GET viacep.com.br", and carries the trace context
+// and baggage written by the global propagator. Query values in the url.full
+// attribute are redacted by the TracerProvider set up by New. A nil base uses
+// http.DefaultTransport.
+// Encapsula base para que cada requisição de saída vire um span de cliente nomeado
+// pelo método e host, ex.: "GET viacep.com.br", e leve o contexto de trace e o baggage
+// escritos pelo propagador global. Os valores da query no atributo url.full são
+// ocultados pelo TracerProvider configurado por New. Uma base nil usa
+// http.DefaultTransport.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Host
+		}),
+	)
+}
+
+// HTTPTracing starts a server span for every request following the OpenTelemetry
+// HTTP semantic conventions: method, status code, client address, body sizes and,
+// once chi has matched it, the route, which also names the span (e.g. "POST /").
+// The incoming trace context is extracted with the global propagator, so handler
+// spans become its children. Health checks are not traced, and the HTTP metrics
+// are left to HTTPMetrics. It must run after DebugSampling, whose flag is read
+// when the span starts.
+// Inicia um span de servidor para cada requisição seguindo as convenções semânticas
+// HTTP do OpenTelemetry: método, status, endereço do cliente, tamanhos dos corpos e,
+// assim que o chi a encontra, a rota, que também nomeia o span (ex.: "POST /").
+// O contexto de trace recebido é extraído com o propagador global, então os spans dos
+// handlers viram seus filhos. Health checks não são rastreados e as métricas HTTP
+// ficam com HTTPMetrics. Deve rodar depois de DebugSampling, cuja marca é lida quando
+// o span começa.
+func HTTPTracing(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		// The route is only known after routing, rename the span on the way out
+		// A rota só é conhecida após o roteamento, o span é renomeado na saída
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil {
+			if pattern := routeContext.RoutePattern(); pattern != "" {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+		}
+	})
+
+	return otelhttp.NewHandler(named, "http-server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method // Until the route is known
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !strings.HasPrefix(r.URL.Path, "/health")
+		}),
+		otelhttp.WithMeterProvider(noop.NewMeterProvider()),
+	)
+}
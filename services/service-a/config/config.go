@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"telemetry/envconfig"
+)
+
+// Config holds every setting of service-a.
+// Values come from the defaults, then the optional YAML file in CONFIG_FILE,
+// then the environment variables, and are validated once at startup.
+// Config guarda todas as configurações do service-a.
+// Os valores vêm dos padrões, depois do arquivo YAML opcional em CONFIG_FILE,
+// depois das variáveis de ambiente, e são validados uma vez na inicialização.
+type Config struct {
+	ServiceName  string `yaml:"service_name"`  // OTEL_SERVICE_NAME
+	Port         string `yaml:"port"`          // PORT
+	OTLPEndpoint string `yaml:"otlp_endpoint"` // OTEL_EXPORTER_OTLP_ENDPOINT
+	ServiceBURL  string `yaml:"service_b_url"` // SERVICE_B_URL
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // SHUTDOWN_TIMEOUT, for draining requests and for flushing spans
+
+	envconfig.Telemetry `yaml:",inline"` // Resource, OTLP, tracing, metrics and log settings
+}
+
+// Default returns the configuration used when nothing is set.
+// Retorna a configuração usada quando nada é definido.
+func Default() *Config {
+	return &Config{
+		ServiceName:  "service-a",
+		Port:         "8080",
+		OTLPEndpoint: "otel-collector:4317",
+		ServiceBURL:  "http://service-b:8081",
+
+		ShutdownTimeout: 10 * time.Second,
+
+		Telemetry: envconfig.DefaultTelemetry(),
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// CONFIG_FILE (if any) and the environment, and validates the result.
+// Monta a configuração a partir dos padrões, do arquivo YAML indicado por
+// CONFIG_FILE (se houver) e do ambiente, e valida o resultado.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the values found in a YAML file, unknown keys are rejected.
+// Sobrepõe os valores encontrados em um arquivo YAML, chaves desconhecidas são rejeitadas.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overlays the values set in the environment.
+// Sobrepõe os valores definidos no ambiente.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	env := envconfig.NewEnv(lookup)
+
+	env.String("OTEL_SERVICE_NAME", &c.ServiceName)
+	env.String("PORT", &c.Port)
+	env.String("OTEL_EXPORTER_OTLP_ENDPOINT", &c.OTLPEndpoint)
+	env.String("SERVICE_B_URL", &c.ServiceBURL)
+	env.Duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+
+	c.Telemetry.LoadEnv(env)
+
+	return env.Err()
+}
+
+// Validate reports every invalid setting at once.
+// Reporta todas as configurações inválidas de uma só vez.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ServiceName != "", "service name must not be empty")
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "port %q must be a number between 1 and 65535", c.Port)
+	check(c.OTLPEndpoint != "", "OTLP endpoint must not be empty")
+	check(c.ShutdownTimeout > 0, "shutdown timeout must be positive")
+	c.Telemetry.Validate(check)
+	check(envconfig.IsHTTPURL(c.ServiceBURL), "service B URL %q must be an absolute http(s) URL", c.ServiceBURL)
+
+	return errors.Join(errs...)
+}
@@ -5,45 +5,59 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
 	"regexp"
 	"service-a/models"
+	"strings"
+	"telemetry"
+	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/metric"
 )
 
-var tracer trace.Tracer
-
-// ForwardRequest lida com a requisição POST do Serviço A
-func ForwardRequest(w http.ResponseWriter, r *http.Request) {
-	// Recupera o nome do serviço da variável de ambiente OTEL_SERVICE_NAME
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = "service-a" // Se não houver, usa o nome "service-a"
+// upstreamDuration registra a latência das chamadas ao Serviço B
+var upstreamDuration, _ = otel.Meter("service-a/handlers").Float64Histogram("upstream.request.duration",
+	metric.WithUnit("s"),
+	metric.WithDescription("Duration of each request sent to an upstream API"),
+	metric.WithExplicitBucketBoundaries(0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10))
+
+// NewForwardRequest cria o handler da requisição POST do Serviço A,
+// com a telemetria do serviço e a URL do Serviço B resolvida pela configuração
+func NewForwardRequest(tel *telemetry.Telemetry, serviceBURL string) http.HandlerFunc {
+	// Cliente instrumentado: cada chamada ao Serviço B vira um span de cliente
+	// e propaga o contexto de trace e o baggage
+	client := &http.Client{Transport: telemetry.NewTransport(nil)}
+	return func(w http.ResponseWriter, r *http.Request) {
+		forwardRequest(tel, client, serviceBURL, w, r)
 	}
-	// Define o tracer para ser usado nas requisições
-	tracer = otel.Tracer(serviceName)
-
-	carrier := propagation.HeaderCarrier(r.Header)
-	context := r.Context()
-	ctx := otel.GetTextMapPropagator().Extract(context, carrier)
-	// Inicia o span para a requisição
-	ctx, span := tracer.Start(ctx, "service-a-request")
+}
+
+// forwardRequest lida com a requisição POST do Serviço A
+func forwardRequest(tel *telemetry.Telemetry, client *http.Client, serviceBURL string, w http.ResponseWriter, r *http.Request) {
+	// Usa o tracer do serviço para os spans da requisição
+	tracer := tel.Tracer()
+
+	// Inicia o span da requisição como filho do span de servidor HTTP do middleware,
+	// que já extraiu o contexto de trace recebido
+	ctx, span := tracer.Start(r.Context(), "service-a-request")
 	defer span.End() // Finaliza o span quando a função terminar
+	// Copia os membros selecionados do baggage (ex.: tenant.id) para o span
+	span.SetAttributes(tel.BaggageAttributes(ctx)...)
 
 	// Decodifica o corpo da requisição
 
-	// Envia o CEP para o Serviço B
-	// Defina a URL do Serviço B no seu docker-compose ou em variável de ambiente
-	serviceBURL := os.Getenv("SERVICE_B_URL") // "http://service-b:8081" por exemplo
-	ctx, validateZipCodeSpan := tracer.Start(ctx, "validate-zip-code")
+	// Os spans das etapas são irmãos sob o span da requisição
+	_, validateZipCodeSpan := tracer.Start(ctx, "validate-zip-code")
 
 	var requestBody models.RequestBody
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || !isValidCep(requestBody.Cep) {
+	validCep := json.NewDecoder(r.Body).Decode(&requestBody) == nil && isValidCep(requestBody.Cep)
+	validateZipCodeSpan.SetAttributes(attribute.Bool("cep.valid", validCep))
+	span.SetAttributes(attribute.Bool("cep.valid", validCep))
+	if !validCep {
 		response := models.ErrorResponse{
 			Error: "invalid zipcode", // Error message in English
 		}
@@ -59,70 +73,81 @@ func ForwardRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validateZipCodeSpan.SetStatus(codes.Ok, "Valid Zip Code Sent")
 	validateZipCodeSpan.End()
 
 	// Envia o CEP para o Serviço B via POST
-	responseBody, statusCode, err := sendToServiceB(ctx, serviceBURL, requestBody.Cep, r)
+	responseBody, statusCode, err := sendToServiceB(ctx, client, serviceBURL, requestBody.Cep, r)
 	if err != nil {
+		slog.ErrorContext(ctx, "error calling service B", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		span.SetStatus(codes.Error, "Error calling Service B") // Marca erro no span
 		return
 	}
 
-	if statusCode == http.StatusNotFound {
-		w.Header().Set("Content-Type", "application/json")
-		response := models.ErrorResponse{
-			Error: "can not find zipcode", // Error message in English
-		}
-
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(response)
-		return
+	// Erros do servidor no Serviço B (ex.: 503 "zipcode lookup unavailable") também
+	// marcam o span; o corpo de erro é repassado como veio
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, "Service B unavailable")
 	}
 
 	// Retorna o status e o corpo de resposta do Serviço B
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode) // Status code de Serviço B
 	json.NewEncoder(w).Encode(responseBody)
-
-	span.SetStatus(codes.Ok, "Successfully Forwarded request")
 }
-func sendToServiceB(ctx context.Context, serviceBURL, cep string, r *http.Request) (body models.ResponseBody, status int, err error) {
+
+// sendToServiceB envia o CEP ao Serviço B e retorna o corpo da resposta: um
+// models.ResponseBody para respostas 2xx e um models.ErrorResponse para as demais
+func sendToServiceB(ctx context.Context, client *http.Client, serviceBURL, cep string, r *http.Request) (body any, status int, err error) {
 	// Decodifica o corpo da resposta do Serviço B
 	var responseBody models.ResponseBody
 	// Cria o corpo da requisição para o Serviço B
 	requestBody := models.RequestBody{Cep: cep}
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return responseBody, 0, fmt.Errorf("failed to marshal request body: %v", err)
+		return nil, 0, fmt.Errorf("failed to marshal request body: %v", err)
 	}
 
-	// Cria uma cópia dos headers
+	// Cria uma cópia dos headers, incluindo o RequestId, sem os cabeçalhos de
+	// propagação recebidos; o trace context atual é injetado pelo transporte do cliente
 	reqHeaders := r.Header.Clone()
-
-	// Propaga os headers, incluindo o RequestId e o trace context
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(reqHeaders))
+	telemetry.RemovePropagationFields(reqHeaders)
 
 	// Cria a requisição POST para o Serviço B com os cabeçalhos modificados
 	req, err := http.NewRequestWithContext(ctx, "POST", serviceBURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return responseBody, 0, fmt.Errorf("failed to create request: %v", err)
+		return nil, 0, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Adiciona os cabeçalhos copiados e modificados na requisição
 	req.Header = reqHeaders
 
 	// Envia a requisição para o Serviço B
-	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	attrs := []attribute.KeyValue{attribute.String("server.address", req.URL.Hostname())}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.type", "transport"))
+	} else {
+		attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	upstreamDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
 	if err != nil {
-		return responseBody, 0, fmt.Errorf("failed to call Service B: %v", err)
+		return nil, 0, fmt.Errorf("failed to call Service B: %v", err)
 	}
 	defer resp.Body.Close()
 
+	// Respostas de erro trazem {"error": "..."}, que é repassado ao cliente
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errorBody models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorBody); err != nil || errorBody.Error == "" {
+			errorBody.Error = strings.ToLower(http.StatusText(resp.StatusCode)) // Corpo fora do formato esperado
+		}
+		return errorBody, resp.StatusCode, nil
+	}
+
 	if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
-		return responseBody, 0, fmt.Errorf("failed to decode response body: %v", err)
+		return nil, 0, fmt.Errorf("failed to decode response body: %v", err)
 	}
 
 	// Retorna a resposta do Serviço B com status e corpo
@@ -1,59 +1,64 @@
 package main
 
 import (
-	"context"
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"service-a/config"
 	"service-a/handlers"
-	helpers "service-a/helpers" // Importando o InitTracer de Helpers/otel.go
+	"telemetry"
 )
 
 func main() {
-	// Recupera o nome do serviço da variável de ambiente OTEL_SERVICE_NAME
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = "service-a" // Se não houver, usa o nome "service-a"
-	}
-
-	otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if otelEndpoint == "" {
-		otelEndpoint = "otel-collector:4317"
-	}
-
-	// Inicializa o Tracer
-	shutdown, err := helpers.InitTracer(serviceName, otelEndpoint)
+	// Carrega e valida a configuração, encerrando com uma mensagem clara se for inválida
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Errorf("error initializing tracer %w", err)
-		// panic("error initializing tracer")
+		log.Fatalf("error loading configuration: %v", err)
 	}
-	defer shutdown(context.Background())
+
+	// Configura o logger JSON estruturado e a telemetria sem esperar pelo collector,
+	// que pode subir depois
+	tel := telemetry.New(cfg.ServiceName,
+		telemetry.WithResource(cfg.Resource.Settings()),
+		telemetry.WithCollector(cfg.OTLPEndpoint, cfg.OTLP.Settings()),
+		telemetry.WithTracing(cfg.Tracing.Settings()),
+		telemetry.WithMetrics(cfg.Metrics.Settings()),
+		telemetry.WithLogs(cfg.Log.Settings()),
+	)
 
 	// Cria o roteador Chi
 	r := chi.NewRouter()
 
+	// Resolve o IP real antes da telemetria, para que o span e o log de cada
+	// requisição tragam o endereço do cliente e não o do proxy
+	r.Use(middleware.RealIP) // Middleware para pegar o IP real
+
+	// Amostra sempre as requisições que trazem o cabeçalho de debug, cria o span de
+	// servidor HTTP de cada requisição, pai dos spans dos handlers, e registra as
+	// métricas RED e um log estruturado por requisição com o trace_id
+	r.Use(tel.Middleware)
+
 	// Adiciona os middlewares do Chi
 	r.Use(middleware.RequestID) // Middleware para RequestID
-	r.Use(middleware.RealIP)    // Middleware para pegar o IP real
 	r.Use(middleware.Recoverer) // Middleware para recuperação de panics
-	r.Use(middleware.Logger)    // Middleware para logging das requisições
 
 	// Configura o handler para a rota POST /
-	r.Post("/", handlers.ForwardRequest)
+	r.Post("/", handlers.NewForwardRequest(tel, cfg.ServiceBURL))
 
-	// Inicia o servidor HTTP na porta 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Default port if not provided
-	}
+	// Expõe a saúde da conexão com o collector de telemetria
+	r.Get("/health/telemetry", tel.HealthHandler())
 
-	fmt.Printf("Serviço A rodando na porta %s...\n", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	// Inicia o servidor HTTP na porta configurada e, ao receber SIGTERM/SIGINT,
+	// drena as requisições em andamento e envia os spans pendentes
+	slog.Info("service A listening", "port", cfg.Port)
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	if err := telemetry.RunServer(server, cfg.ShutdownTimeout, tel.Shutdown); err != nil {
+		slog.Error("server error", "error", err)
+		os.Exit(1)
 	}
 }
@@ -10,6 +10,7 @@ type ResponseBody struct {
 	Fahrenheit float64 `json:"temp_F"`
 	Kelvin     float64 `json:"temp_K"`
 	City       string  `json:"city"`
+	Stale      bool    `json:"stale,omitempty"` // Temperature served from Service B's cache after its upstream failed
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,267 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrOpen is returned without calling the upstream while the breaker is open.
+// Retornado sem chamar o upstream enquanto o disjuntor está aberto.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+// Estado de um disjuntor.
+type State int
+
+const (
+	Closed   State = iota // Calls flow and outcomes are recorded
+	Open                  // Calls fail fast until OpenTimeout elapses
+	HalfOpen              // A few probe calls decide whether to close or reopen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Settings configures when a breaker trips and recovers.
+// Configura quando um disjuntor abre e se recupera.
+type Settings struct {
+	WindowSize           int              // Number of recent calls considered for the failure rate
+	MinRequests          int              // Calls needed in the window before the breaker may trip
+	FailureRateThreshold float64          // Failure rate (0..1) that trips the breaker
+	SlowCallThreshold    time.Duration    // Calls slower than this count as failures, zero disables it
+	OpenTimeout          time.Duration    // Time spent open before probing the upstream again
+	HalfOpenMaxCalls     int              // Probe calls allowed, and successes needed to close
+	IsFailure            func(error) bool // Classifies errors, every error is a failure when nil
+}
+
+// DefaultSettings returns the settings used when nothing is configured.
+// Retorna as configurações usadas quando nada é configurado.
+func DefaultSettings() Settings {
+	return Settings{
+		WindowSize:           20,
+		MinRequests:          10,
+		FailureRateThreshold: 0.5,
+		SlowCallThreshold:    5 * time.Second,
+		OpenTimeout:          30 * time.Second,
+		HalfOpenMaxCalls:     3,
+	}
+}
+
+// Breaker is a closed/open/half-open circuit breaker guarding a single upstream.
+// Every state change is added as an event to the current span and recorded as metrics.
+// Breaker é um disjuntor fechado/aberto/meio-aberto protegendo um único upstream.
+// Cada mudança de estado é adicionada como evento ao span atual e registrada como métrica.
+type Breaker struct {
+	name     string   // Upstream guarded by the breaker
+	settings Settings // Thresholds
+
+	mu               sync.Mutex
+	state            State     // Current state
+	outcomes         []bool    // Ring of recent outcomes, true for failures
+	next             int       // Next slot in outcomes
+	recorded         int       // Outcomes recorded since the window was reset
+	openedAt         time.Time // When the breaker last opened
+	halfOpenInFlight int       // Probe calls currently running
+	halfOpenSuccess  int       // Successful probe calls
+	generation       uint64    // Incremented on every state change, tells calls admitted in an earlier state apart
+
+	transitions metric.Int64Counter // State changes, by upstream and target state
+	stateGauge  metric.Int64Gauge   // Current state, by upstream
+}
+
+// New creates a closed breaker for the named upstream, recording its metrics with meter.
+// Cria um disjuntor fechado para o upstream informado, registrando suas métricas com meter.
+func New(name string, settings Settings, meter metric.Meter) *Breaker {
+	defaults := DefaultSettings()
+	if settings.WindowSize < 1 {
+		settings.WindowSize = defaults.WindowSize
+	}
+	if settings.MinRequests < 1 || settings.MinRequests > settings.WindowSize {
+		settings.MinRequests = settings.WindowSize
+	}
+	if settings.HalfOpenMaxCalls < 1 {
+		settings.HalfOpenMaxCalls = defaults.HalfOpenMaxCalls
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	transitions, _ := meter.Int64Counter("circuit_breaker.transitions",
+		metric.WithDescription("Circuit breaker state changes"))
+	stateGauge, _ := meter.Int64Gauge("circuit_breaker.state",
+		metric.WithDescription("Circuit breaker state: 0 closed, 1 open, 2 half-open"))
+
+	return &Breaker{
+		name:        name,
+		settings:    settings,
+		outcomes:    make([]bool, settings.WindowSize),
+		transitions: transitions,
+		stateGauge:  stateGauge,
+	}
+}
+
+// Name returns the upstream guarded by the breaker.
+// Retorna o upstream protegido pelo disjuntor.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+// Retorna o estado atual do disjuntor.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		return HalfOpen // Will switch on the next call
+	}
+	return b.state
+}
+
+// Execute runs fn unless the breaker is open, in which case ErrOpen is returned
+// right away. Calls abandoned because ctx was cancelled are not counted.
+// Executa fn, a menos que o disjuntor esteja aberto, caso em que ErrOpen é
+// retornado imediatamente. Chamadas abandonadas pelo cancelamento de ctx não contam.
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	generation, err := b.before(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = fn(ctx)
+	elapsed := time.Since(start)
+
+	if err != nil && ctx.Err() != nil {
+		b.abandon(generation) // The caller gave up, this says nothing about the upstream
+		return err
+	}
+
+	failed := b.settings.IsFailure(err) ||
+		(b.settings.SlowCallThreshold > 0 && elapsed > b.settings.SlowCallThreshold)
+	b.after(ctx, generation, failed)
+	return err
+}
+
+// before decides whether a call may go through and returns the generation it was
+// admitted in.
+// Decide se uma chamada pode prosseguir e retorna a geração em que foi admitida.
+func (b *Breaker) before(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		if time.Since(b.openedAt) < b.settings.OpenTimeout {
+			return 0, ErrOpen
+		}
+		b.transition(ctx, HalfOpen)
+	}
+	if b.state == HalfOpen {
+		if b.halfOpenInFlight >= b.settings.HalfOpenMaxCalls {
+			return 0, ErrOpen // Enough probes are already running
+		}
+		b.halfOpenInFlight++
+	}
+	return b.generation, nil
+}
+
+// abandon releases a probe slot without recording an outcome.
+// Libera uma vaga de teste sem registrar resultado.
+func (b *Breaker) abandon(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == HalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+}
+
+// after records the outcome of a call and trips or resets the breaker.
+// Registra o resultado de uma chamada e abre ou fecha o disjuntor.
+func (b *Breaker) after(ctx context.Context, generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A call admitted in an earlier state, e.g. closed before the breaker opened, is
+	// not a probe and says nothing about the current state
+	// Uma chamada admitida em um estado anterior, ex.: fechado antes de o disjuntor
+	// abrir, não é um teste e não diz nada sobre o estado atual
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case HalfOpen:
+		if b.halfOpenInFlight > 0 {
+			b.halfOpenInFlight--
+		}
+		if failed {
+			b.transition(ctx, Open)
+			return
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.settings.HalfOpenMaxCalls {
+			b.transition(ctx, Closed)
+		}
+	case Closed:
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+		if b.recorded < len(b.outcomes) {
+			b.recorded++
+		}
+		if b.recorded >= b.settings.MinRequests && b.failureRate() >= b.settings.FailureRateThreshold {
+			b.transition(ctx, Open)
+		}
+	}
+}
+
+// failureRate returns the share of failures in the window; the caller must hold the lock.
+// Retorna a proporção de falhas na janela; quem chama deve possuir o lock.
+func (b *Breaker) failureRate() float64 {
+	failures := 0
+	for i := 0; i < b.recorded; i++ {
+		if b.outcomes[i] {
+			failures++
+		}
+	}
+	return float64(failures) / float64(b.recorded)
+}
+
+// transition switches state, resets the counters and reports the change;
+// the caller must hold the lock.
+// Troca de estado, zera os contadores e reporta a mudança; quem chama deve possuir o lock.
+func (b *Breaker) transition(ctx context.Context, to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.halfOpenInFlight, b.halfOpenSuccess = 0, 0
+	if to == Open {
+		b.openedAt = time.Now()
+	}
+	if to == Closed {
+		b.next, b.recorded = 0, 0
+		clear(b.outcomes)
+	}
+
+	upstream := attribute.String("upstream", b.name)
+	trace.SpanFromContext(ctx).AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+		upstream,
+		attribute.String("circuit_breaker.from", from.String()),
+		attribute.String("circuit_breaker.to", to.String()),
+	))
+	b.transitions.Add(ctx, 1, metric.WithAttributes(upstream, attribute.String("state", to.String())))
+	b.stateGauge.Record(ctx, int64(to), metric.WithAttributes(upstream))
+}
@@ -0,0 +1,190 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var errUpstream = errors.New("upstream failed")
+
+func succeed(context.Context) error { return nil }
+
+func fail(context.Context) error { return errUpstream }
+
+// testSettings trips after 4 calls at 50% failures and probes twice after 20ms.
+// Abre após 4 chamadas com 50% de falhas e testa duas vezes após 20ms.
+func testSettings() Settings {
+	return Settings{
+		WindowSize:           4,
+		MinRequests:          4,
+		FailureRateThreshold: 0.5,
+		OpenTimeout:          20 * time.Millisecond,
+		HalfOpenMaxCalls:     2,
+	}
+}
+
+func newTestBreaker(settings Settings) *Breaker {
+	return New("upstream", settings, noop.NewMeterProvider().Meter("test"))
+}
+
+// trip opens the breaker with failed calls.
+// Abre o disjuntor com chamadas que falham.
+func trip(t *testing.T, b *Breaker) {
+	t.Helper()
+	for b.State() == Closed {
+		require.ErrorIs(t, b.Execute(context.Background(), fail), errUpstream)
+	}
+	require.Equal(t, Open, b.State())
+}
+
+// blockingCall starts a call that ends with the error sent to the returned channel,
+// which is closed once Execute returned.
+// Inicia uma chamada que termina com o erro enviado ao canal retornado, que é fechado
+// quando Execute retorna.
+func blockingCall(b *Breaker) (release chan error, done chan struct{}) {
+	release, done, started := make(chan error), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Execute(context.Background(), func(context.Context) error {
+			close(started)
+			return <-release
+		})
+	}()
+	<-started
+	return release, done
+}
+
+func TestBreakerTripsOnTheFailureRate(t *testing.T) {
+	b := newTestBreaker(testSettings())
+
+	// Below MinRequests nothing trips, even at 100% failures
+	for range 3 {
+		require.ErrorIs(t, b.Execute(context.Background(), fail), errUpstream)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	// The fourth call reaches MinRequests with 100% failures
+	require.ErrorIs(t, b.Execute(context.Background(), fail), errUpstream)
+	assert.Equal(t, Open, b.State())
+
+	called := false
+	err := b.Execute(context.Background(), func(context.Context) error { called = true; return nil })
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called, "an open breaker must not call the upstream")
+}
+
+func TestBreakerStaysClosedBelowTheFailureRate(t *testing.T) {
+	b := newTestBreaker(testSettings())
+
+	for _, call := range []func(context.Context) error{succeed, succeed, succeed, fail, succeed, succeed} {
+		b.Execute(context.Background(), call)
+	}
+
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerTripsOnSlowCalls(t *testing.T) {
+	settings := testSettings()
+	settings.SlowCallThreshold = 5 * time.Millisecond
+	b := newTestBreaker(settings)
+
+	slow := func(context.Context) error { time.Sleep(10 * time.Millisecond); return nil }
+	for range 4 {
+		require.NoError(t, b.Execute(context.Background(), slow))
+	}
+
+	assert.Equal(t, Open, b.State())
+}
+
+func TestBreakerIgnoresCancelledCalls(t *testing.T) {
+	b := newTestBreaker(testSettings())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for range 8 {
+		b.Execute(ctx, func(ctx context.Context) error { return ctx.Err() })
+	}
+
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerProbesAfterTheOpenTimeout(t *testing.T) {
+	b := newTestBreaker(testSettings())
+	trip(t, b)
+
+	assert.ErrorIs(t, b.Execute(context.Background(), succeed), ErrOpen, "still within the open timeout")
+	time.Sleep(testSettings().OpenTimeout)
+	assert.Equal(t, HalfOpen, b.State())
+
+	// Probes are limited to HalfOpenMaxCalls at once
+	firstRelease, firstDone := blockingCall(b)
+	secondRelease, secondDone := blockingCall(b)
+	assert.ErrorIs(t, b.Execute(context.Background(), succeed), ErrOpen, "a third probe must wait")
+
+	// HalfOpenMaxCalls successes close the breaker
+	firstRelease <- nil
+	<-firstDone
+	assert.Equal(t, HalfOpen, b.State())
+	secondRelease <- nil
+	<-secondDone
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerReopensOnAFailedProbe(t *testing.T) {
+	b := newTestBreaker(testSettings())
+	trip(t, b)
+	time.Sleep(testSettings().OpenTimeout)
+
+	require.ErrorIs(t, b.Execute(context.Background(), fail), errUpstream)
+
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Execute(context.Background(), succeed), ErrOpen)
+}
+
+func TestBreakerIgnoresCallsAdmittedBeforeTheStateChanged(t *testing.T) {
+	settings := testSettings()
+	settings.HalfOpenMaxCalls = 1
+	b := newTestBreaker(settings)
+
+	// A call admitted while closed is still running when the breaker opens...
+	staleRelease, staleDone := blockingCall(b)
+	trip(t, b)
+	time.Sleep(settings.OpenTimeout)
+
+	// ...and ends successfully while a real probe runs
+	probeRelease, probeDone := blockingCall(b)
+	staleRelease <- nil
+	<-staleDone
+
+	assert.Equal(t, HalfOpen, b.State(), "a stale success must not close the breaker")
+	assert.ErrorIs(t, b.Execute(context.Background(), succeed), ErrOpen, "a stale call must not free the probe slot")
+
+	probeRelease <- nil
+	<-probeDone
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerRecordsTransitionsWithTheGivenMeter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	b := New("upstream", testSettings(), provider.Meter("test"))
+
+	trip(t, b)
+
+	var metrics metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &metrics))
+	require.Len(t, metrics.ScopeMetrics, 1)
+	var names []string
+	for _, m := range metrics.ScopeMetrics[0].Metrics {
+		names = append(names, m.Name)
+	}
+	assert.ElementsMatch(t, []string{"circuit_breaker.transitions", "circuit_breaker.state"}, names)
+}
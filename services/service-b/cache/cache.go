@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Cache is a byte oriented key/value store with per-entry TTL. It backs the CEP
+// and weather caches so they can live in process or be shared between replicas.
+// Every backend treats a ttl of zero or less as "do not cache": nothing is stored
+// and any previous value is removed (Redis would otherwise keep it forever).
+// Cache é um armazenamento chave/valor de bytes com TTL por entrada. Ele sustenta
+// os caches de CEP e clima para que fiquem em memória ou compartilhados entre réplicas.
+// Todo backend trata um ttl zero ou negativo como "não guardar": nada é gravado e
+// qualquer valor anterior é removido (o Redis o manteria para sempre).
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)                  // Value stored under key, if any.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error // Store value under key for ttl, a ttl <= 0 only removes key.
+	Delete(ctx context.Context, key string) error                               // Remove key.
+}
+
+// SchemaVersion is the version of the cached payloads. Bump it whenever a cached
+// type changes shape so entries written by older releases are ignored.
+// Versão dos valores em cache. Incremente sempre que um tipo em cache mudar de
+// formato para que entradas gravadas por versões antigas sejam ignoradas.
+const SchemaVersion = 1
+
+// ErrVersionMismatch is returned by Decode for payloads written with another SchemaVersion.
+// Retornado por Decode para valores gravados com outra SchemaVersion.
+var ErrVersionMismatch = errors.New("cache: schema version mismatch")
+
+// envelope wraps every cached payload with its schema version.
+// Envolve todo valor em cache com a versão do seu formato.
+type envelope struct {
+	Version int             `json:"v"`
+	Data    json.RawMessage `json:"d"`
+}
+
+// Encode serializes value into a versioned payload.
+// Serializa value em um valor versionado.
+func Encode(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{Version: SchemaVersion, Data: data})
+}
+
+// Decode deserializes a versioned payload into out.
+// Desserializa um valor versionado em out.
+func Decode(payload []byte, out any) error {
+	var e envelope
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	if e.Version != SchemaVersion {
+		return ErrVersionMismatch
+	}
+	return json.Unmarshal(e.Data, out)
+}
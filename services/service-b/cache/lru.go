@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded, thread-safe cache whose entries expire after a TTL.
+// When full, the least recently used entry is evicted.
+// LRU é um cache limitado por tamanho e seguro para concorrência cujas entradas
+// expiram após um TTL. Quando cheio, a entrada usada há mais tempo é descartada.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex          // Guards every field below
+	capacity int                 // Maximum number of entries
+	order    *list.List          // Entries from most to least recently used
+	items    map[K]*list.Element // Entries indexed by key
+	now      func() time.Time    // Clock used to expire entries
+}
+
+// entry is a single cached value.
+// Um único valor em cache.
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU creates an LRU holding at most capacity entries.
+// Cria um LRU com no máximo capacity entradas.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[K]*list.Element{},
+		now:      time.Now,
+	}
+}
+
+// Get returns the value stored under key if it has not expired yet.
+// Retorna o valor guardado em key caso ainda não tenha expirado.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := element.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt) {
+		c.remove(element) // Drop expired entries lazily
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry if needed.
+// Guarda value em key por ttl, descartando a entrada menos usada se necessário.
+func (c *LRU[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete removes key from the cache.
+// Remove key do cache.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len returns the number of entries, expired ones included.
+// Retorna o número de entradas, incluindo as expiradas.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// remove drops an element; the caller must hold the lock.
+// Remove um elemento; quem chama deve possuir o lock.
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Memory is an in-process Cache backed by an LRU.
+// Memory é um Cache em memória sustentado por um LRU.
+type Memory struct {
+	entries *LRU[string, []byte] // Cached payloads indexed by key
+}
+
+// NewMemory creates an in-process Cache holding at most size entries.
+// Cria um Cache em memória com no máximo size entradas.
+func NewMemory(size int) *Memory {
+	return &Memory{entries: NewLRU[string, []byte](size)}
+}
+
+// Get returns the payload stored under key if it has not expired yet.
+// Retorna o valor guardado em key caso ainda não tenha expirado.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := m.entries.Get(key)
+	return value, ok, nil
+}
+
+// Set stores value under key for ttl, a ttl <= 0 only removes key.
+// Guarda value em key por ttl, um ttl <= 0 apenas remove key.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		m.entries.Delete(key)
+		return nil
+	}
+	m.entries.Set(key, value, ttl)
+	return nil
+}
+
+// Delete removes key from the cache.
+// Remove key do cache.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.entries.Delete(key)
+	return nil
+}
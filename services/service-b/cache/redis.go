@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Cache shared between replicas through a Redis server.
+// Redis é um Cache compartilhado entre réplicas através de um servidor Redis.
+type Redis struct {
+	client redis.UniversalClient // Client used for every command
+	prefix string                // Prepended to every key
+}
+
+// NewRedis creates a Redis backed Cache on top of an existing client. Any
+// Redis compatible server works, including an embedded miniredis.
+// Cria um Cache sustentado pelo Redis a partir de um cliente existente. Qualquer
+// servidor compatível com Redis funciona, incluindo um miniredis embutido.
+func NewRedis(client redis.UniversalClient, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// NewRedisClient creates a Redis client whose commands are traced as child spans.
+// Cria um cliente Redis cujos comandos são rastreados como spans filhos.
+func NewRedisClient(options *redis.Options) (*redis.Client, error) {
+	client := redis.NewClient(options)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Get returns the payload stored under key, if any.
+// Retorna o valor guardado em key, se existir.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl, a ttl <= 0 only removes key. Redis itself
+// would read a zero expiration as "never expires".
+// Guarda value em key por ttl, um ttl <= 0 apenas remove key. O próprio Redis
+// leria uma expiração zero como "nunca expira".
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return r.Delete(ctx, key)
+	}
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+// Delete removes key from the cache.
+// Remove key do cache.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedis returns a Redis cache on top of an embedded miniredis server.
+// Retorna um cache Redis sobre um servidor miniredis embutido.
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client, "test:"), server
+}
+
+type cachedCity struct {
+	City string `json:"city"`
+	UF   string `json:"uf"`
+}
+
+func TestRedisRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedis(t)
+
+	payload, err := Encode(cachedCity{City: "São Paulo", UF: "SP"})
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "01001000", payload, time.Minute))
+	assert.True(t, server.Exists("test:01001000"), "keys are prefixed")
+
+	stored, ok, err := store.Get(ctx, "01001000")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var city cachedCity
+	require.NoError(t, Decode(stored, &city))
+	assert.Equal(t, cachedCity{City: "São Paulo", UF: "SP"}, city)
+
+	server.FastForward(time.Minute)
+	_, ok, err = store.Get(ctx, "01001000")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired entries are reported as missing")
+}
+
+func TestRedisMissingKey(t *testing.T) {
+	store, _ := newTestRedis(t)
+
+	// redis.Nil is a miss, not an error
+	// redis.Nil é uma ausência, não um erro
+	value, ok, err := store.Get(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, value)
+}
+
+func TestRedisVersionMismatch(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedis(t)
+
+	old, err := json.Marshal(envelope{Version: SchemaVersion - 1, Data: json.RawMessage(`{"city":"Recife"}`)})
+	require.NoError(t, err)
+	require.NoError(t, server.Set("test:50010000", string(old)))
+
+	stored, ok, err := store.Get(ctx, "50010000")
+	require.NoError(t, err)
+	require.True(t, ok)
+	var city cachedCity
+	assert.ErrorIs(t, Decode(stored, &city), ErrVersionMismatch)
+}
+
+func TestZeroTTLStoresNothing(t *testing.T) {
+	ctx := context.Background()
+	redisStore, server := newTestRedis(t)
+	for name, store := range map[string]Cache{"redis": redisStore, "memory": NewMemory(10)} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.Set(ctx, "key", []byte("old"), time.Minute))
+			require.NoError(t, store.Set(ctx, "key", []byte("new"), 0))
+
+			_, ok, err := store.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.False(t, ok, "a zero TTL must not keep any value")
+		})
+	}
+	assert.False(t, server.Exists("test:key"))
+}
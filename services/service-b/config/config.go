@@ -0,0 +1,551 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"service-b/breaker"
+	"service-b/services"
+	"telemetry/envconfig"
+)
+
+// Config holds every setting of service-b.
+// Values come from the defaults, then the optional YAML file in CONFIG_FILE,
+// then the environment variables, and are validated once at startup.
+// Config guarda todas as configurações do service-b.
+// Os valores vêm dos padrões, depois do arquivo YAML opcional em CONFIG_FILE,
+// depois das variáveis de ambiente, e são validados uma vez na inicialização.
+type Config struct {
+	ServiceName  string `yaml:"service_name"`  // OTEL_SERVICE_NAME
+	Port         string `yaml:"port"`          // PORT
+	OTLPEndpoint string `yaml:"otlp_endpoint"` // OTEL_EXPORTER_OTLP_ENDPOINT
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // SHUTDOWN_TIMEOUT, for draining requests and for flushing spans
+
+	envconfig.Telemetry `yaml:",inline"` // Resource, OTLP, tracing, metrics and log settings
+
+	Upstream UpstreamConfig `yaml:"upstream"`
+	CEP      CEPConfig      `yaml:"cep"`
+	Weather  WeatherConfig  `yaml:"weather"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Breaker  BreakerConfig  `yaml:"breaker"`
+	Retry    RetryConfig    `yaml:"retry"`
+	Hedge    HedgeConfig    `yaml:"hedge"`
+}
+
+// UpstreamConfig bounds the calls made to external APIs.
+// Limita as chamadas feitas às APIs externas.
+type UpstreamConfig struct {
+	Timeout  time.Duration            `yaml:"timeout"`  // UPSTREAM_TIMEOUT
+	Timeouts map[string]time.Duration `yaml:"timeouts"` // UPSTREAM_TIMEOUTS ("host=duration,...")
+}
+
+// CEPConfig selects the CEP providers and sizes their cache.
+// Seleciona os provedores de CEP e dimensiona o seu cache.
+type CEPConfig struct {
+	Providers        []string      `yaml:"providers"`          // CEP_PROVIDERS, in race order
+	CacheSize        int           `yaml:"cache_size"`         // CEP_CACHE_SIZE
+	CacheTTL         time.Duration `yaml:"cache_ttl"`          // CEP_CACHE_TTL
+	CacheNegativeTTL time.Duration `yaml:"cache_negative_ttl"` // CEP_CACHE_NEGATIVE_TTL
+}
+
+// WeatherConfig selects the weather backends, their keys and sizes their cache.
+// Seleciona os backends de clima, suas chaves e dimensiona o seu cache.
+type WeatherConfig struct {
+	Providers            []string         `yaml:"providers"`              // WEATHER_PROVIDERS, in failover order
+	APIKey               envconfig.Secret `yaml:"api_key"`                // WEATHER_API_KEY or WEATHER_API_KEY_FILE
+	OpenWeatherMapAPIKey envconfig.Secret `yaml:"openweathermap_api_key"` // OPENWEATHERMAP_API_KEY or OPENWEATHERMAP_API_KEY_FILE
+	CacheSize            int              `yaml:"cache_size"`             // WEATHER_CACHE_SIZE
+	CacheTTL             time.Duration    `yaml:"cache_ttl"`              // WEATHER_CACHE_TTL
+	CacheMaxStale        time.Duration    `yaml:"cache_max_stale"`        // WEATHER_CACHE_MAX_STALE
+}
+
+// ActiveProviders splits the distinct weather providers, in failover order, into
+// the usable ones and the keyed ones skipped because their API key is missing, so
+// a missing WEATHER_API_KEY fails over to the keyless backends instead of
+// stopping the service.
+// Separa os provedores de clima distintos, na ordem de failover, entre os que podem
+// ser usados e os que exigem chave e foram ignorados por falta dela, para que a
+// ausência de WEATHER_API_KEY recorra aos backends sem chave em vez de impedir o
+// serviço de subir.
+func (c WeatherConfig) ActiveProviders() (active, skipped []string) {
+	for _, name := range distinctNames(c.Providers) {
+		switch {
+		case name == "weatherapi" && c.APIKey == "",
+			name == "openweathermap" && c.OpenWeatherMapAPIKey == "":
+			skipped = append(skipped, name)
+		default:
+			active = append(active, name)
+		}
+	}
+	return active, skipped
+}
+
+// CacheConfig selects the backend shared by the CEP and weather caches.
+// Seleciona o backend compartilhado pelos caches de CEP e clima.
+type CacheConfig struct {
+	Backend string      `yaml:"backend"` // CACHE_BACKEND ("memory" or "redis")
+	Redis   RedisConfig `yaml:"redis"`
+}
+
+// RedisConfig holds the connection settings of the Redis cache backend.
+// Guarda as configurações de conexão do backend de cache Redis.
+type RedisConfig struct {
+	Addr      string           `yaml:"addr"`       // REDIS_ADDR
+	Password  envconfig.Secret `yaml:"password"`   // REDIS_PASSWORD or REDIS_PASSWORD_FILE
+	DB        int              `yaml:"db"`         // REDIS_DB
+	KeyPrefix string           `yaml:"key_prefix"` // REDIS_KEY_PREFIX
+}
+
+// BreakerConfig holds the circuit breaker thresholds shared by every upstream.
+// Guarda os limites dos disjuntores compartilhados por todos os upstreams.
+type BreakerConfig struct {
+	WindowSize    int           `yaml:"window_size"`     // BREAKER_WINDOW_SIZE
+	MinRequests   int           `yaml:"min_requests"`    // BREAKER_MIN_REQUESTS
+	FailureRate   float64       `yaml:"failure_rate"`    // BREAKER_FAILURE_RATE
+	SlowCall      time.Duration `yaml:"slow_call"`       // BREAKER_SLOW_CALL
+	OpenTimeout   time.Duration `yaml:"open_timeout"`    // BREAKER_OPEN_TIMEOUT
+	HalfOpenCalls int           `yaml:"half_open_calls"` // BREAKER_HALF_OPEN_CALLS
+}
+
+// RetryConfig holds the default retry policy and the policies of specific upstreams.
+// Guarda a política de repetição padrão e as políticas de upstreams específicos.
+type RetryConfig struct {
+	RetryPolicyConfig `yaml:",inline"` // RETRY_MAX_ATTEMPTS, RETRY_INITIAL_BACKOFF, ...
+
+	Upstreams map[string]RetryPolicyConfig `yaml:"upstreams"` // UPSTREAM_RETRY_ATTEMPTS, UPSTREAM_RETRY_INITIAL_BACKOFF, ... ("host=value,..."), by host
+}
+
+// RetryPolicyConfig describes one retry policy. In an upstream entry, fields left
+// at zero keep the value of the default policy.
+// Descreve uma política de repetição. Em uma entrada de upstream, campos deixados
+// em zero mantêm o valor da política padrão.
+type RetryPolicyConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // RETRY_MAX_ATTEMPTS
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // RETRY_INITIAL_BACKOFF
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // RETRY_MAX_BACKOFF
+	Multiplier     float64       `yaml:"multiplier"`      // RETRY_MULTIPLIER
+	Jitter         float64       `yaml:"jitter"`          // RETRY_JITTER, fraction of the delay drawn at random
+	StatusCodes    []int         `yaml:"status_codes"`    // RETRY_STATUS_CODES ("429,503,..."; "429|503" per upstream)
+}
+
+// HedgeConfig controls the hedging of the primary weather backend.
+// Controla o hedge do backend de clima primário.
+type HedgeConfig struct {
+	Mode         string        `yaml:"mode"`          // HEDGE_MODE ("off", "same" or "alternate")
+	Percentile   float64       `yaml:"percentile"`    // HEDGE_PERCENTILE
+	DefaultDelay time.Duration `yaml:"default_delay"` // HEDGE_DEFAULT_DELAY
+	MinDelay     time.Duration `yaml:"min_delay"`     // HEDGE_MIN_DELAY
+}
+
+// Default returns the configuration used when nothing is set.
+// Retorna a configuração usada quando nada é definido.
+func Default() *Config {
+	breakerSettings := breaker.DefaultSettings()
+	retryPolicy := services.DefaultRetryPolicy()
+	hedgeSettings := services.DefaultHedgeSettings()
+
+	return &Config{
+		ServiceName:  "service-b",
+		Port:         "8081",
+		OTLPEndpoint: "otel-collector:4317",
+
+		ShutdownTimeout: 10 * time.Second,
+
+		Telemetry: envconfig.DefaultTelemetry(),
+
+		Upstream: UpstreamConfig{
+			Timeout:  services.DefaultUpstreamTimeout,
+			Timeouts: map[string]time.Duration{},
+		},
+		CEP: CEPConfig{
+			Providers:        services.DefaultCEPProviders,
+			CacheSize:        services.DefaultCEPCacheSize,
+			CacheTTL:         services.DefaultCEPCacheTTL,
+			CacheNegativeTTL: services.DefaultCEPCacheNegativeTTL,
+		},
+		Weather: WeatherConfig{
+			Providers:     services.DefaultWeatherProviders,
+			CacheSize:     services.DefaultWeatherCacheSize,
+			CacheTTL:      services.DefaultWeatherCacheTTL,
+			CacheMaxStale: services.DefaultWeatherCacheMaxStale,
+		},
+		Cache: CacheConfig{
+			Backend: "memory",
+			Redis:   RedisConfig{KeyPrefix: "service-b:"},
+		},
+		Breaker: BreakerConfig{
+			WindowSize:    breakerSettings.WindowSize,
+			MinRequests:   breakerSettings.MinRequests,
+			FailureRate:   breakerSettings.FailureRateThreshold,
+			SlowCall:      breakerSettings.SlowCallThreshold,
+			OpenTimeout:   breakerSettings.OpenTimeout,
+			HalfOpenCalls: breakerSettings.HalfOpenMaxCalls,
+		},
+		Retry: RetryConfig{
+			RetryPolicyConfig: RetryPolicyConfig{
+				MaxAttempts:    retryPolicy.MaxAttempts,
+				InitialBackoff: retryPolicy.InitialBackoff,
+				MaxBackoff:     retryPolicy.MaxBackoff,
+				Multiplier:     retryPolicy.Multiplier,
+				Jitter:         retryPolicy.Jitter,
+				StatusCodes:    retryPolicy.RetryableStatusCodes,
+			},
+			Upstreams: map[string]RetryPolicyConfig{},
+		},
+		Hedge: HedgeConfig{
+			Mode:         "off",
+			Percentile:   hedgeSettings.Percentile,
+			DefaultDelay: hedgeSettings.DefaultDelay,
+			MinDelay:     hedgeSettings.MinDelay,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// CONFIG_FILE (if any) and the environment, and validates the result.
+// Monta a configuração a partir dos padrões, do arquivo YAML indicado por
+// CONFIG_FILE (se houver) e do ambiente, e valida o resultado.
+func Load() (*Config, error) {
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the values found in a YAML file, unknown keys are rejected.
+// Sobrepõe os valores encontrados em um arquivo YAML, chaves desconhecidas são rejeitadas.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overlays the values set in the environment.
+// Sobrepõe os valores definidos no ambiente.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	env := envconfig.NewEnv(lookup)
+
+	env.String("OTEL_SERVICE_NAME", &c.ServiceName)
+	env.String("PORT", &c.Port)
+	env.String("OTEL_EXPORTER_OTLP_ENDPOINT", &c.OTLPEndpoint)
+	env.Duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+
+	env.Duration("UPSTREAM_TIMEOUT", &c.Upstream.Timeout)
+	durationMap(env, "UPSTREAM_TIMEOUTS", &c.Upstream.Timeouts)
+
+	env.List("CEP_PROVIDERS", &c.CEP.Providers)
+	env.Int("CEP_CACHE_SIZE", &c.CEP.CacheSize)
+	env.Duration("CEP_CACHE_TTL", &c.CEP.CacheTTL)
+	env.Duration("CEP_CACHE_NEGATIVE_TTL", &c.CEP.CacheNegativeTTL)
+
+	env.List("WEATHER_PROVIDERS", &c.Weather.Providers)
+	env.Secret("WEATHER_API_KEY", &c.Weather.APIKey)
+	env.Secret("OPENWEATHERMAP_API_KEY", &c.Weather.OpenWeatherMapAPIKey)
+	env.Int("WEATHER_CACHE_SIZE", &c.Weather.CacheSize)
+	env.Duration("WEATHER_CACHE_TTL", &c.Weather.CacheTTL)
+	env.Duration("WEATHER_CACHE_MAX_STALE", &c.Weather.CacheMaxStale)
+
+	env.String("CACHE_BACKEND", &c.Cache.Backend)
+	env.String("REDIS_ADDR", &c.Cache.Redis.Addr)
+	env.Secret("REDIS_PASSWORD", &c.Cache.Redis.Password)
+	env.Int("REDIS_DB", &c.Cache.Redis.DB)
+	env.String("REDIS_KEY_PREFIX", &c.Cache.Redis.KeyPrefix)
+
+	env.Int("BREAKER_WINDOW_SIZE", &c.Breaker.WindowSize)
+	env.Int("BREAKER_MIN_REQUESTS", &c.Breaker.MinRequests)
+	env.Float("BREAKER_FAILURE_RATE", &c.Breaker.FailureRate)
+	env.Duration("BREAKER_SLOW_CALL", &c.Breaker.SlowCall)
+	env.Duration("BREAKER_OPEN_TIMEOUT", &c.Breaker.OpenTimeout)
+	env.Int("BREAKER_HALF_OPEN_CALLS", &c.Breaker.HalfOpenCalls)
+
+	env.Int("RETRY_MAX_ATTEMPTS", &c.Retry.MaxAttempts)
+	env.Duration("RETRY_INITIAL_BACKOFF", &c.Retry.InitialBackoff)
+	env.Duration("RETRY_MAX_BACKOFF", &c.Retry.MaxBackoff)
+	env.Float("RETRY_MULTIPLIER", &c.Retry.Multiplier)
+	env.Float("RETRY_JITTER", &c.Retry.Jitter)
+	env.IntList("RETRY_STATUS_CODES", &c.Retry.StatusCodes)
+	upstreamRetry(env, "UPSTREAM_RETRY_ATTEMPTS", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) (err error) {
+		policy.MaxAttempts, err = strconv.Atoi(value)
+		return err
+	})
+	upstreamRetry(env, "UPSTREAM_RETRY_INITIAL_BACKOFF", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) (err error) {
+		policy.InitialBackoff, err = time.ParseDuration(value)
+		return err
+	})
+	upstreamRetry(env, "UPSTREAM_RETRY_MAX_BACKOFF", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) (err error) {
+		policy.MaxBackoff, err = time.ParseDuration(value)
+		return err
+	})
+	upstreamRetry(env, "UPSTREAM_RETRY_MULTIPLIER", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) (err error) {
+		policy.Multiplier, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	upstreamRetry(env, "UPSTREAM_RETRY_JITTER", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) (err error) {
+		policy.Jitter, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	upstreamRetry(env, "UPSTREAM_RETRY_STATUS_CODES", &c.Retry.Upstreams, func(policy *RetryPolicyConfig, value string) error {
+		policy.StatusCodes = nil
+		for _, code := range strings.Split(value, "|") {
+			number, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return err
+			}
+			policy.StatusCodes = append(policy.StatusCodes, number)
+		}
+		return nil
+	})
+
+	env.String("HEDGE_MODE", &c.Hedge.Mode)
+	env.Float("HEDGE_PERCENTILE", &c.Hedge.Percentile)
+	env.Duration("HEDGE_DEFAULT_DELAY", &c.Hedge.DefaultDelay)
+	env.Duration("HEDGE_MIN_DELAY", &c.Hedge.MinDelay)
+
+	c.Telemetry.LoadEnv(env)
+
+	return env.Err()
+}
+
+// Validate reports every invalid setting at once.
+// Reporta todas as configurações inválidas de uma só vez.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ServiceName != "", "service name must not be empty")
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "port %q must be a number between 1 and 65535", c.Port)
+	check(c.OTLPEndpoint != "", "OTLP endpoint must not be empty")
+	check(c.ShutdownTimeout > 0, "shutdown timeout must be positive")
+	c.Telemetry.Validate(check)
+
+	check(c.Upstream.Timeout >= 0, "upstream timeout must not be negative")
+	for host, timeout := range c.Upstream.Timeouts {
+		check(timeout > 0, "upstream timeout for %s must be positive", host)
+	}
+
+	check(len(c.CEP.Providers) > 0, "at least one CEP provider is required")
+	check(c.CEP.CacheSize > 0, "CEP cache size must be positive")
+	check(c.CEP.CacheTTL >= 0 && c.CEP.CacheNegativeTTL >= 0, "CEP cache TTLs must not be negative")
+
+	check(len(c.Weather.Providers) > 0, "at least one weather provider is required")
+	// Keyed providers without a key are skipped at startup, so only fail when nothing is left
+	// Provedores sem a chave exigida são ignorados na inicialização, então só falha quando nada resta
+	if active, skipped := c.Weather.ActiveProviders(); len(active) == 0 && len(skipped) > 0 {
+		check(false, "no usable weather provider, API key missing for %s (WEATHER_API_KEY, OPENWEATHERMAP_API_KEY); set it or add a keyless provider such as open-meteo", strings.Join(skipped, ", "))
+	}
+	check(c.Weather.CacheSize > 0, "weather cache size must be positive")
+	check(c.Weather.CacheTTL >= 0 && c.Weather.CacheMaxStale >= 0, "weather cache TTLs must not be negative")
+
+	switch c.Cache.Backend {
+	case "memory":
+	case "redis":
+		check(c.Cache.Redis.Addr != "", "cache backend redis needs REDIS_ADDR")
+		check(c.Cache.Redis.DB >= 0, "redis database must not be negative")
+	default:
+		check(false, "unknown cache backend %q (available: memory, redis)", c.Cache.Backend)
+	}
+
+	check(c.Breaker.WindowSize > 0, "breaker window size must be positive")
+	check(c.Breaker.MinRequests > 0, "breaker minimum requests must be positive")
+	check(c.Breaker.FailureRate > 0 && c.Breaker.FailureRate <= 1, "breaker failure rate %v must be in (0, 1]", c.Breaker.FailureRate)
+	check(c.Breaker.SlowCall >= 0, "breaker slow call threshold must not be negative")
+	check(c.Breaker.OpenTimeout > 0, "breaker open timeout must be positive")
+	check(c.Breaker.HalfOpenCalls > 0, "breaker half-open calls must be positive")
+
+	c.Retry.RetryPolicyConfig.validate("retry", check)
+	for host, upstream := range c.Retry.Upstreams {
+		// Checked merged with the default, a half-set override may still conflict with it
+		// Validada junto com a padrão, uma sobreposição parcial ainda pode conflitar com ela
+		c.Retry.merge(upstream).validate("retry for "+host, check)
+	}
+
+	switch c.Hedge.Mode {
+	case "off", "same":
+	case "alternate":
+		active, _ := c.Weather.ActiveProviders()
+		check(len(active) >= 2, "hedge mode alternate needs at least two distinct weather providers with their API keys")
+	default:
+		check(false, "unknown hedge mode %q (available: off, same, alternate)", c.Hedge.Mode)
+	}
+	if c.Hedge.Mode != "off" {
+		check(c.Hedge.Percentile > 0 && c.Hedge.Percentile <= 1, "hedge percentile %v must be in (0, 1]", c.Hedge.Percentile)
+		check(c.Hedge.DefaultDelay > 0, "hedge default delay must be positive")
+		check(c.Hedge.MinDelay >= 0, "hedge minimum delay must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// distinctNames normalizes provider names like the registries do, dropping blanks
+// and duplicates, e.g. "weatherapi,WeatherAPI" is a single provider.
+// Normaliza nomes de provedores como os registros fazem, descartando vazios e
+// duplicados, ex.: "weatherapi,WeatherAPI" é um único provedor.
+func distinctNames(names []string) []string {
+	var distinct []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(distinct, name) {
+			distinct = append(distinct, name)
+		}
+	}
+	return distinct
+}
+
+// upstreamRetry reads a "host=value,..." list into the retry policies by host,
+// parsing each value into its policy with set.
+// Lê uma lista "host=valor,..." nas políticas de repetição por host, lendo cada
+// valor na sua política com set.
+func upstreamRetry(env *envconfig.Env, name string, dst *map[string]RetryPolicyConfig, set func(policy *RetryPolicyConfig, value string) error) {
+	var values map[string]string
+	env.StringMap(name, &values)
+	for host, value := range values {
+		host = strings.ToLower(host)
+		policy := (*dst)[host]
+		if err := set(&policy, value); err != nil {
+			env.Fail(name, fmt.Errorf("entry %s=%s: %w", host, value, err))
+			return
+		}
+		if *dst == nil {
+			*dst = map[string]RetryPolicyConfig{}
+		}
+		(*dst)[host] = policy
+	}
+}
+
+// durationMap reads a "host=duration,..." list.
+// Lê uma lista "host=duração,...".
+func durationMap(env *envconfig.Env, name string, dst *map[string]time.Duration) {
+	value, ok := env.Value(name)
+	if !ok {
+		return
+	}
+	timeouts, err := services.ParseUpstreamTimeouts(value)
+	if err != nil {
+		env.Fail(name, err)
+		return
+	}
+	*dst = timeouts
+}
+
+// Settings converts the thresholds into breaker settings.
+// Converte os limites em configurações de disjuntor.
+func (c BreakerConfig) Settings() breaker.Settings {
+	settings := breaker.DefaultSettings()
+	settings.WindowSize = c.WindowSize
+	settings.MinRequests = c.MinRequests
+	settings.FailureRateThreshold = c.FailureRate
+	settings.SlowCallThreshold = c.SlowCall
+	settings.OpenTimeout = c.OpenTimeout
+	settings.HalfOpenMaxCalls = c.HalfOpenCalls
+	return settings
+}
+
+// Policy converts the settings into the default retry policy.
+// Converte as configurações na política de repetição padrão.
+func (c RetryConfig) Policy() services.RetryPolicy {
+	return c.RetryPolicyConfig.policy()
+}
+
+// Policies returns the retry policy of each configured upstream, its own values
+// replacing those of the default policy.
+// Retorna a política de repetição de cada upstream configurado, seus próprios valores
+// substituindo os da política padrão.
+func (c RetryConfig) Policies() map[string]services.RetryPolicy {
+	policies := make(map[string]services.RetryPolicy, len(c.Upstreams))
+	for host, upstream := range c.Upstreams {
+		policies[strings.ToLower(host)] = c.merge(upstream).policy()
+	}
+	return policies
+}
+
+// merge returns the default policy with the non-zero fields of upstream.
+// Retorna a política padrão com os campos não zerados de upstream.
+func (c RetryConfig) merge(upstream RetryPolicyConfig) RetryPolicyConfig {
+	merged := c.RetryPolicyConfig
+	if upstream.MaxAttempts != 0 {
+		merged.MaxAttempts = upstream.MaxAttempts
+	}
+	if upstream.InitialBackoff != 0 {
+		merged.InitialBackoff = upstream.InitialBackoff
+	}
+	if upstream.MaxBackoff != 0 {
+		merged.MaxBackoff = upstream.MaxBackoff
+	}
+	if upstream.Multiplier != 0 {
+		merged.Multiplier = upstream.Multiplier
+	}
+	if upstream.Jitter != 0 {
+		merged.Jitter = upstream.Jitter
+	}
+	if upstream.StatusCodes != nil {
+		merged.StatusCodes = upstream.StatusCodes
+	}
+	return merged
+}
+
+// policy converts the settings into a retry policy.
+// Converte as configurações em uma política de repetição.
+func (c RetryPolicyConfig) policy() services.RetryPolicy {
+	policy := services.DefaultRetryPolicy()
+	policy.MaxAttempts = c.MaxAttempts
+	policy.InitialBackoff = c.InitialBackoff
+	policy.MaxBackoff = c.MaxBackoff
+	policy.Multiplier = c.Multiplier
+	policy.Jitter = c.Jitter
+	policy.RetryableStatusCodes = c.StatusCodes
+	return policy
+}
+
+// validate checks one retry policy, scope prefixes the messages.
+// Valida uma política de repetição, scope prefixa as mensagens.
+func (c RetryPolicyConfig) validate(scope string, check func(bool, string, ...any)) {
+	check(c.MaxAttempts >= 1, "%s max attempts must be at least 1", scope)
+	check(c.InitialBackoff >= 0, "%s initial backoff must not be negative", scope)
+	check(c.MaxBackoff >= c.InitialBackoff, "%s max backoff must not be below the initial backoff", scope)
+	check(c.Multiplier >= 1, "%s multiplier %v must be at least 1", scope, c.Multiplier)
+	check(c.Jitter >= 0 && c.Jitter <= 1, "%s jitter %v must be between 0 and 1", scope, c.Jitter)
+	for _, code := range c.StatusCodes {
+		check(code >= 100 && code <= 599, "%s status code %d is not a valid HTTP status", scope, code)
+	}
+}
+
+// Settings converts the settings into hedge settings.
+// Converte as configurações em configurações de hedge.
+func (c HedgeConfig) Settings() services.HedgeSettings {
+	settings := services.DefaultHedgeSettings()
+	settings.Percentile = c.Percentile
+	settings.DefaultDelay = c.DefaultDelay
+	settings.MinDelay = c.MinDelay
+	return settings
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookupFrom serves the environment from vars.
+// Fornece o ambiente a partir de vars.
+func lookupFrom(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestUpstreamRetryPoliciesReplaceTheDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+retry:
+  max_attempts: 4
+  initial_backoff: 50ms
+  upstreams:
+    api.weatherapi.com:
+      max_attempts: 2
+      initial_backoff: 1s
+      max_backoff: 10s
+      jitter: 0.2
+      status_codes: [503]
+`), 0o600))
+	cfg := Default()
+	require.NoError(t, cfg.loadFile(path))
+	require.NoError(t, cfg.loadEnv(lookupFrom(map[string]string{
+		"RETRY_MULTIPLIER":               "3",
+		"UPSTREAM_RETRY_ATTEMPTS":        "ViaCEP.com.br=5",
+		"UPSTREAM_RETRY_MULTIPLIER":      "viacep.com.br=1.5",
+		"UPSTREAM_RETRY_STATUS_CODES":    "viacep.com.br=429|502",
+		"UPSTREAM_RETRY_INITIAL_BACKOFF": "api.weatherapi.com=2s",
+	})))
+	require.NoError(t, cfg.Validate())
+
+	defaultPolicy := cfg.Retry.Policy()
+	assert.Equal(t, 4, defaultPolicy.MaxAttempts)
+	assert.Equal(t, 50*time.Millisecond, defaultPolicy.InitialBackoff)
+	assert.Equal(t, 3.0, defaultPolicy.Multiplier)
+
+	policies := cfg.Retry.Policies()
+	weather := policies["api.weatherapi.com"]
+	assert.Equal(t, 2, weather.MaxAttempts)
+	assert.Equal(t, 2*time.Second, weather.InitialBackoff, "the environment overrides the file")
+	assert.Equal(t, 10*time.Second, weather.MaxBackoff)
+	assert.Equal(t, 0.2, weather.Jitter)
+	assert.Equal(t, []int{503}, weather.RetryableStatusCodes)
+	assert.Equal(t, 3.0, weather.Multiplier, "unset fields keep the default policy")
+
+	viaCEP := policies["viacep.com.br"]
+	assert.Equal(t, 5, viaCEP.MaxAttempts)
+	assert.Equal(t, 1.5, viaCEP.Multiplier)
+	assert.Equal(t, []int{429, 502}, viaCEP.RetryableStatusCodes)
+	assert.Equal(t, defaultPolicy.InitialBackoff, viaCEP.InitialBackoff)
+	assert.Equal(t, defaultPolicy.MaxBackoff, viaCEP.MaxBackoff)
+	assert.Equal(t, defaultPolicy.Jitter, viaCEP.Jitter)
+}
+
+func TestUpstreamRetryPoliciesAreValidated(t *testing.T) {
+	cfg := Default()
+	require.NoError(t, cfg.loadEnv(lookupFrom(map[string]string{
+		"RETRY_MAX_BACKOFF":              "1s",
+		"UPSTREAM_RETRY_INITIAL_BACKOFF": "viacep.com.br=5s",
+		"UPSTREAM_RETRY_JITTER":          "viacep.com.br=2",
+		"UPSTREAM_RETRY_STATUS_CODES":    "viacep.com.br=999",
+	})))
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retry for viacep.com.br max backoff must not be below the initial backoff")
+	assert.Contains(t, err.Error(), "retry for viacep.com.br jitter 2 must be between 0 and 1")
+	assert.Contains(t, err.Error(), "retry for viacep.com.br status code 999 is not a valid HTTP status")
+}
+
+func TestUpstreamRetryRejectsMalformedEntries(t *testing.T) {
+	err := Default().loadEnv(lookupFrom(map[string]string{
+		"UPSTREAM_RETRY_ATTEMPTS":     "viacep.com.br=many",
+		"UPSTREAM_RETRY_STATUS_CODES": "viacep.com.br=429|oops",
+	}))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "UPSTREAM_RETRY_ATTEMPTS")
+	assert.Contains(t, err.Error(), "UPSTREAM_RETRY_STATUS_CODES")
+}
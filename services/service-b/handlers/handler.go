@@ -2,16 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"os"
 	"service-b/models"
 	"service-b/services"
 	"service-b/shared"
+	"telemetry"
 
-	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -23,24 +24,31 @@ type WeatherHandler struct {
 	WeatherService       services.WeatherService      // Service to retrieve weather data
 	CepValidator         *shared.CepValidator         // Validator for validating CEP (Brazilian ZIP code)
 	TemperatureConverter *shared.TemperatureConverter // Utility to convert temperatures between Celsius, Fahrenheit, and Kelvin
+	Telemetry            *telemetry.Telemetry         // Service telemetry, copies the selected baggage members into the request span
+	Tracer               trace.Tracer                 // Tracer named after the service, used for the request spans
+	Temperatures         metric.Float64Histogram      // Temperatures returned to clients, by UF
 }
 
 // NewWeatherHandler creates and returns a new WeatherHandler with everything initialized
 // Nova instância do WeatherHandler é criada e retornada com todos os serviços e utilitários inicializados
 func NewWeatherHandler(
+	tel *telemetry.Telemetry,
 	locationService services.LocationService,
 	weatherService services.WeatherService,
 	temperatureConverter *shared.TemperatureConverter,
-	chBrasilAPI, chViaCEP chan models.Location,
 ) *WeatherHandler {
-	// Initialize channels for fetching location data
-	// Inicializa os canais para buscar dados de localização
-
+	temperatures, _ := tel.Meter().Float64Histogram("weather.temperature",
+		metric.WithUnit("Cel"),
+		metric.WithDescription("Temperatures returned to clients, by UF"),
+		metric.WithExplicitBucketBoundaries(-5, 0, 5, 10, 15, 20, 25, 30, 35, 40, 45))
 	return &WeatherHandler{
 		LocationService:      locationService,                   // Assign location service
 		WeatherService:       weatherService,                    // Assign weather service
 		CepValidator:         shared.NewCepValidator(`^\d{8}$`), // Assign CEP validator with a regex pattern
 		TemperatureConverter: temperatureConverter,              // Assign temperature converter utility
+		Telemetry:            tel,                               // Assign the service telemetry
+		Tracer:               tel.Tracer(),                      // Assign the service tracer
+		Temperatures:         temperatures,                      // Assign the temperature histogram
 	}
 }
 
@@ -48,27 +56,19 @@ type RequestBody struct {
 	Cep string `json:"cep"`
 }
 
-var tracer trace.Tracer
-
 // WeatherHandlerFunc handles the HTTP requests for weather data
 // Função que lida com as requisições HTTP para obter dados meteorológicos
 func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		tracer := h.Tracer
 
-		serviceName := os.Getenv("OTEL_SERVICE_NAME")
-		if serviceName == "" {
-			serviceName = "service-b" // Se não houver, usa o nome "service-a"
-		}
-		tracer = otel.Tracer(serviceName)
-
-		carrier := propagation.HeaderCarrier(r.Header)
-		context := r.Context()
-		ctx := otel.GetTextMapPropagator().Extract(context, carrier)
-
-		// Cria o span para o serviço B
-		ctx, serviceBRequestSpan := tracer.Start(ctx, "service-b-request")
+		// Cria o span para o serviço B como filho do span de servidor HTTP do middleware,
+		// que já extraiu o contexto de trace recebido
+		ctx, serviceBRequestSpan := tracer.Start(r.Context(), "service-b-request")
 
 		defer serviceBRequestSpan.End()
+		// Copia os membros selecionados do baggage (ex.: tenant.id) para o span
+		serviceBRequestSpan.SetAttributes(h.Telemetry.BaggageAttributes(ctx)...)
 		// Decodificando o corpo da requisição para obter o CEP
 		var requestBody RequestBody
 		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
@@ -76,22 +76,15 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		if tracer == nil {
-			log.Println("Tracer is nil! There is a problem with initialization.")
-			http.Error(w, "Tracer initialization failed", http.StatusInternalServerError)
-			return
-		} else {
-			log.Println("Tracer initialized successfully")
-		}
-		ctx, validateZipCodeSpan := tracer.Start(ctx, "validating-zip-code")
+		// Os spans das etapas são irmãos sob o span da requisição
+		_, validateZipCodeSpan := tracer.Start(ctx, "validating-zip-code")
 
-		// Create channels for receiving location data from APIs
-		// Cria canais para receber dados de localização das APIs
-		chBrasilAPI := make(chan models.Location)
-		chViaCEP := make(chan models.Location)
 		// Validate the CEP input
 		// Valida o CEP fornecido
-		if !h.CepValidator.IsValidCep(requestBody.Cep) {
+		validCep := h.CepValidator.IsValidCep(requestBody.Cep)
+		validateZipCodeSpan.SetAttributes(attribute.Bool("cep.valid", validCep))
+		serviceBRequestSpan.SetAttributes(attribute.Bool("cep.valid", validCep))
+		if !validCep {
 			// Respond with an error message if CEP is invalid
 			// Retorna uma resposta de erro caso o CEP seja inválido
 			response := models.ErrorResponse{
@@ -110,14 +103,33 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 
 			return
 		}
-		validateZipCodeSpan.SetStatus(codes.Ok, "Valid Zip Code Sent")
-		serviceBRequestSpan.SetStatus(codes.Ok, "Valid Zip Code Sent")
 		validateZipCodeSpan.End()
 
-		ctx, getLocationFromZipCodeSpan := tracer.Start(ctx, "getting-zip-code-information")
-		// Fetch location data based on CEP, using channels to simulate multiple API responses
-		// Busca dados de localização com base no CEP, utilizando canais para simular múltiplas respostas de APIs
-		location, err := h.LocationService.GetLocationFromCEP(requestBody.Cep, chBrasilAPI, chViaCEP)
+		locationCtx, getLocationFromZipCodeSpan := tracer.Start(ctx, "getting-zip-code-information")
+		// Fetch location data based on CEP, racing every configured provider
+		// Busca dados de localização com base no CEP, disputando entre os provedores configurados
+		location, err := h.LocationService.GetLocationFromCEP(locationCtx, requestBody.Cep)
+		if err != nil && !errors.Is(err, services.ErrCEPNotFound) {
+			// Respond with an error message if no provider could answer
+			// Retorna uma resposta de erro caso nenhum provedor consiga responder
+			response := models.ErrorResponse{
+				Error: "zipcode lookup unavailable", // Error message in English
+			}
+			// Set the HTTP status code to 503 (Service Unavailable)
+			// Define o código de status HTTP como 503 (Serviço indisponível)
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			// Encode the response into JSON and send it to the client
+			// Codifica a resposta em JSON e envia para o cliente
+			json.NewEncoder(w).Encode(response)
+			slog.ErrorContext(locationCtx, "zip code providers unavailable", "cep", requestBody.Cep, "error", err)
+			getLocationFromZipCodeSpan.RecordError(err)
+			getLocationFromZipCodeSpan.SetStatus(codes.Error, "Zip code providers unavailable")
+			serviceBRequestSpan.SetStatus(codes.Error, "Zip code providers unavailable")
+			getLocationFromZipCodeSpan.End()
+
+			return
+		}
 		if err != nil || location.City == nil {
 			// Respond with an error message if the location cannot be found
 			// Retorna uma resposta de erro caso não seja possível encontrar a localização
@@ -137,14 +149,22 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 
 			return
 		}
-		getLocationFromZipCodeSpan.SetStatus(codes.Ok, "Found Zip Code")
-		serviceBRequestSpan.SetStatus(codes.Ok, "Found Zip Code")
+		var uf string
+		if location.Uf != nil {
+			uf = *location.Uf
+		}
+		locationAttributes := []attribute.KeyValue{
+			attribute.String("location.city", *location.City),
+			attribute.String("location.uf", uf),
+		}
+		getLocationFromZipCodeSpan.SetAttributes(locationAttributes...)
+		serviceBRequestSpan.SetAttributes(locationAttributes...)
 		getLocationFromZipCodeSpan.End()
 
-		ctx, getTemperatureSpan := tracer.Start(ctx, "getting-temperature-information")
+		temperatureCtx, getTemperatureSpan := tracer.Start(ctx, "getting-temperature-information")
 		// Fetch temperature for the city
 		// Busca a temperatura para a cidade
-		tempC, err := h.WeatherService.GetTemperature(*location.City)
+		temperature, err := h.WeatherService.GetTemperature(temperatureCtx, *location.City, uf)
 		if err != nil {
 			// Respond with an error message if fetching the temperature fails
 			// Retorna uma resposta de erro caso a busca pela temperatura falhe
@@ -158,6 +178,7 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 			// Encode the response into JSON and send it to the client
 			// Codifica a resposta em JSON e envia para o cliente
 			json.NewEncoder(w).Encode(response)
+			slog.ErrorContext(temperatureCtx, "failed to get temperature", "city", *location.City, "uf", uf, "error", err)
 			getTemperatureSpan.SetStatus(codes.Error, "failed to get temperature")
 			serviceBRequestSpan.SetStatus(codes.Error, "failed to get temperature")
 			getTemperatureSpan.End()
@@ -165,8 +186,9 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 			return
 		}
 
-		getTemperatureSpan.SetStatus(codes.Ok, "Found Temperature")
-		serviceBRequestSpan.SetStatus(codes.Ok, "Found Temperature")
+		tempC := temperature.Celsius
+		h.Temperatures.Record(ctx, tempC, metric.WithAttributes(attribute.String("location.uf", uf)))
+		getTemperatureSpan.SetAttributes(attribute.Bool("weather.stale", temperature.Stale))
 		getTemperatureSpan.End()
 
 		// Convert temperature using the shared utility
@@ -177,16 +199,16 @@ func (h *WeatherHandler) WeatherHandlerFunc() http.HandlerFunc {
 		// Prepare the response with temperature data in Celsius, Fahrenheit, and Kelvin
 		// Prepara a resposta com os dados de temperatura em Celsius, Fahrenheit e Kelvin
 		response := models.TemperatureResponse{
-			Celsius:    tempC,          // Temperature in Celsius
-			Fahrenheit: tempF,          // Temperature in Fahrenheit
-			Kelvin:     tempK,          // Temperature in Kelvin
-			City:       *location.City, // City
+			Celsius:    tempC,             // Temperature in Celsius
+			Fahrenheit: tempF,             // Temperature in Fahrenheit
+			Kelvin:     tempK,             // Temperature in Kelvin
+			City:       *location.City,    // City
+			Stale:      temperature.Stale, // Served from cache after the upstream failed
 		}
 
 		// Send the response as JSON
 		// Envia a resposta como JSON
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-		serviceBRequestSpan.SetStatus(codes.Ok, "Finished Request Successfully")
 	}
 }
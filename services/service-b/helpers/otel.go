@@ -1,53 +0,0 @@
-package helpers
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-)
-
-func InitTracer(serviceName string, collectorURL string) (func(context.Context) error, error) {
-	ctx := context.Background()
-
-	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
-
-	if err != nil {
-		fmt.Println("Failed to create Resource %w", err)
-		return nil, fmt.Errorf("Failed to create Resource %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-	conn, err := grpc.DialContext(ctx, collectorURL, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
-
-	if err != nil {
-		fmt.Println("Failed to create Resource %w", err)
-		return nil, fmt.Errorf("Failed to create GRPC connection to collector %w", err)
-	}
-
-	traceExporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
-
-	if err != nil {
-		fmt.Println("Failed to create Resource %w", err)
-		return nil, fmt.Errorf("Failed to create trace exporter %w", err)
-	}
-
-	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
-	traceProvider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithResource(res), sdktrace.WithSpanProcessor(bsp))
-
-	otel.SetTracerProvider(traceProvider)
-
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	return traceExporter.Shutdown, nil
-
-}
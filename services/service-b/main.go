@@ -4,26 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 
+	"service-b/breaker"
+	"service-b/cache"
+	"service-b/config"
 	handlers "service-b/handlers"
-	"service-b/helpers"
-	"service-b/models"
 	"service-b/services"
 	"service-b/shared"
+	"telemetry"
 )
 
 // getHandler initializes and returns a new instance of WeatherHandler.
 // Inicializa e retorna uma nova instância de WeatherHandler.
-func getHandler() *handlers.WeatherHandler {
-	// Create channels for receiving location data from APIs
-	// Cria canais para receber dados de localização das APIs
-	chBrasilAPI := make(chan models.Location)
-	chViaCEP := make(chan models.Location)
-
+func getHandler(cfg *config.Config, tel *telemetry.Telemetry) (*handlers.WeatherHandler, error) {
 	// Create an HTTP client
 	// Cria um cliente HTTP
 	client := &http.Client{}
@@ -32,70 +31,216 @@ func getHandler() *handlers.WeatherHandler {
 	// Inicializa o conversor de temperatura
 	temperatureConverter := &shared.TemperatureConverter{}
 
-	// Initialize API client with the HTTP client
-	// Inicializa o cliente da API com o cliente HTTP
-	apiClient := &services.APIClientImpl{Client: client}
+	// Initialize API client with the HTTP client, traced and bounded by the upstream timeouts
+	// Inicializa o cliente da API com o cliente HTTP, rastreado e limitado pelos timeouts
+	apiClient := services.NewAPIClient(client, cfg.Upstream.Timeouts, cfg.Upstream.Timeout)
+
+	// Apply the default retry policy and the per-upstream attempts
+	// Aplica a política de repetição padrão e as tentativas por upstream
+	apiClient.DefaultRetryPolicy = cfg.Retry.Policy()
+	apiClient.RetryPolicies = cfg.Retry.Policies()
+
+	// Circuit breaker thresholds shared by every upstream
+	// Limites dos disjuntores compartilhados por todos os upstreams
+	breakerSettings := cfg.Breaker.Settings()
+
+	// Build the weather backends in failover order, skipping those whose API key is missing
+	// Constrói os backends de clima na ordem de failover, ignorando os que não têm a chave de API
+	activeWeatherProviders, skippedWeatherProviders := cfg.Weather.ActiveProviders()
+	for _, name := range skippedWeatherProviders {
+		slog.Warn("weather provider disabled, its API key is missing", "provider", name)
+	}
+	weatherProviders, err := services.DefaultWeatherProviderRegistry().Build(activeWeatherProviders, apiClient, services.WeatherProviderKeys{
+		WeatherAPI:     string(cfg.Weather.APIKey),
+		OpenWeatherMap: string(cfg.Weather.OpenWeatherMapAPIKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, provider := range weatherProviders {
+		weatherProviders[i] = services.NewBreakerWeatherProvider(provider, breakerSettings, tel.Meter())
+	}
+
+	// Optionally hedge the primary weather backend, according to the hedge mode
+	// Opcionalmente faz hedge do backend de clima primário, conforme o modo de hedge
+	weatherProviders, err = hedgeWeatherProviders(weatherProviders, cfg.Hedge, breakerSettings, tel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select the cache backend shared by the CEP and weather caches
+	// Seleciona o backend de cache usado pelos caches de CEP e clima
+	newCache, err := cacheFactory(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new instance of WeatherService with the configured backends, behind the cache
+	// Cria uma nova instância do WeatherService com os backends configurados, atrás do cache
+	weatherService := services.NewCachedWeatherService(
+		services.NewWeatherService(weatherProviders),
+		newCache(cfg.Weather.CacheSize),
+		cfg.Weather.CacheTTL,
+		cfg.Weather.CacheMaxStale,
+	)
 
-	// Create a new instance of WeatherService with the API client
-	// Cria uma nova instância do WeatherService com o cliente da API
-	weatherService := services.NewWeatherService(apiClient)
+	// Build the CEP providers in race order
+	// Constrói os provedores de CEP na ordem da disputa
+	providers, err := services.DefaultCEPProviderRegistry().Build(cfg.CEP.Providers, apiClient)
+	if err != nil {
+		return nil, err
+	}
+	for i, provider := range providers {
+		providers[i] = services.NewBreakerCEPProvider(provider, breakerSettings, tel.Meter())
+	}
 
-	// Initialize LocationService which depends on WeatherService
-	// Inicializa o LocationService, que depende do WeatherService
-	locationService := services.NewLocationService(weatherService)
+	// Initialize LocationService with the configured CEP providers, behind the cache
+	// Inicializa o LocationService com os provedores de CEP configurados, atrás do cache
+	locationService := services.NewCachedLocationService(
+		services.NewLocationService(providers),
+		newCache(cfg.CEP.CacheSize),
+		cfg.CEP.CacheTTL,
+		cfg.CEP.CacheNegativeTTL,
+		tel.Tracer(),
+	)
 
-	// Initialize and return WeatherHandler with the necessary services and channels
-	// Inicializa e retorna o WeatherHandler com os serviços e canais necessários
+	// Initialize and return WeatherHandler with the necessary services
+	// Inicializa e retorna o WeatherHandler com os serviços necessários
 	handler := handlers.NewWeatherHandler(
+		tel,
 		locationService,
 		weatherService,
 		temperatureConverter,
-		chBrasilAPI,
-		chViaCEP,
 	)
-	return handler
+	return handler, nil
+}
+
+// cacheFactory selects the cache backend ("memory" or "redis").
+// In-process caches get their own size; Redis caches share a single traced client.
+// Seleciona o backend de cache ("memory" ou "redis").
+// Caches em memória têm seu próprio tamanho; caches Redis compartilham um único cliente rastreado.
+func cacheFactory(cfg config.CacheConfig) (func(size int) cache.Cache, error) {
+	if cfg.Backend != "redis" {
+		return func(size int) cache.Cache { return cache.NewMemory(size) }, nil
+	}
+	client, err := cache.NewRedisClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: string(cfg.Redis.Password),
+		DB:       cfg.Redis.DB,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error initializing redis client: %w", err)
+	}
+	store := cache.NewRedis(client, cfg.Redis.KeyPrefix)
+	return func(int) cache.Cache { return store }, nil
+}
+
+// hedgeWeatherProviders hedges the primary weather backend according to the mode:
+// "off" (default), "same" to hedge against the primary itself or "alternate" to
+// hedge against the next backend in the failover order. The legs are traced with the
+// tracer of tel; in "same" mode the hedge leg gets a breaker of its own built from
+// breakerSettings, so a single outage is not counted twice by the primary's breaker.
+// Faz hedge do backend de clima primário conforme o modo: "off" (padrão),
+// "same" para repetir no próprio primário ou "alternate" para usar o próximo
+// backend na ordem de failover. As pernas são rastreadas com o tracer de tel; no modo
+// "same" a perna de hedge ganha um disjuntor próprio criado a partir de
+// breakerSettings, para que uma única falha não seja contada duas vezes pelo
+// disjuntor do primário.
+func hedgeWeatherProviders(providers []services.WeatherProvider, cfg config.HedgeConfig, breakerSettings breaker.Settings, tel *telemetry.Telemetry) ([]services.WeatherProvider, error) {
+	tracer := tel.Tracer()
+	switch cfg.Mode {
+	case "same":
+		hedge := providers[0]
+		if guarded, ok := hedge.(*services.BreakerWeatherProvider); ok {
+			hedge = &services.BreakerWeatherProvider{
+				WeatherProvider: guarded.WeatherProvider,
+				Breaker:         breaker.New(guarded.Name()+"-hedge", breakerSettings, tel.Meter()),
+			}
+		}
+		providers[0] = services.NewHedgedWeatherProvider(providers[0], hedge, cfg.Settings(), tracer)
+		return providers, nil
+	case "alternate":
+		// Counted after Build, which drops duplicated names
+		// Contados depois do Build, que descarta nomes duplicados
+		if len(providers) < 2 {
+			return nil, fmt.Errorf("hedge mode alternate needs at least two weather providers, got %d", len(providers))
+		}
+		// The alternate is folded into the hedge so failover does not ask it twice
+		// O alternativo é incorporado ao hedge para que o failover não o consulte duas vezes
+		hedged := services.NewHedgedWeatherProvider(providers[0], providers[1], cfg.Settings(), tracer)
+		return append([]services.WeatherProvider{hedged}, providers[2:]...), nil
+	default:
+		return providers, nil
+	}
 }
 
 // main function that starts the HTTP server
 // Função main que inicia o servidor HTTP
 func main() {
-	// Recupera o nome do serviço da variável de ambiente OTEL_SERVICE_NAME
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = "service-b" // Se não houver, usa o nome "service-b"
+	// Carrega e valida a configuração, encerrando com uma mensagem clara se for inválida
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("error loading configuration: %v", err)
 	}
 
-	otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if otelEndpoint == "" {
-		otelEndpoint = "otel-collector:4317"
+	// Os erros já foram registrados por run, que também enviou a telemetria pendente
+	if err := run(cfg); err != nil {
+		os.Exit(1)
 	}
+}
 
-	// Inicializa o Tracer
-	shutdown, err := helpers.InitTracer(serviceName, otelEndpoint)
-	if err != nil {
-		fmt.Errorf("error initializing tracer %w", err)
-		// panic("error initializing tracer")
-	}
-	defer shutdown(context.Background())
+// run sets up the telemetry and serves HTTP until a shutdown signal. Every failure is
+// logged and the pending telemetry flushed before it returns, so the caller may exit.
+// Configura a telemetria e serve HTTP até um sinal de encerramento. Toda falha é
+// registrada e a telemetria pendente enviada antes do retorno, para que quem chama
+// possa encerrar o processo.
+func run(cfg *config.Config) error {
+	// Configura o logger JSON estruturado e a telemetria sem esperar pelo collector,
+	// que pode subir depois
+	tel := telemetry.New(cfg.ServiceName,
+		telemetry.WithResource(cfg.Resource.Settings()),
+		telemetry.WithCollector(cfg.OTLPEndpoint, cfg.OTLP.Settings()),
+		telemetry.WithTracing(cfg.Tracing.Settings()),
+		telemetry.WithMetrics(cfg.Metrics.Settings()),
+		telemetry.WithLogs(cfg.Log.Settings()),
+	)
 
 	// Cria o roteador Chi
 	r := chi.NewRouter()
 
-	// Obtém o handler de clima para lidar com requisições relacionadas ao clima
-	weatherHandler := getHandler()
+	// Amostra sempre as requisições que trazem o cabeçalho de debug, cria o span de
+	// servidor HTTP de cada requisição, pai dos spans dos handlers, e registra as
+	// métricas RED e um log estruturado por requisição com o trace_id
+	r.Use(tel.Middleware)
+
+	// Obtém o handler de clima para lidar com requisições relacionadas ao clima; se
+	// falhar, envia a telemetria já registrada (inclusive este erro) antes de desistir
+	weatherHandler, err := getHandler(cfg, tel)
+	if err != nil {
+		slog.Error("error initializing weather handler", "error", err)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if shutdownErr := tel.Shutdown(ctx); shutdownErr != nil {
+			slog.Warn("cleanup failed during shutdown", "error", shutdownErr)
+		}
+		return err
+	}
 
 	// Define a rota para os dados do clima e associa com o WeatherHandler
 	r.Post("/", weatherHandler.WeatherHandlerFunc()) // Mudando para método POST
 
-	// Obtém o número da porta da variável de ambiente, padrão para "8081" se não estiver definida
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081" // Default port if not provided
-	}
+	// Expõe a saúde da conexão com o collector de telemetria
+	r.Get("/health/telemetry", tel.HealthHandler())
 
 	// Registra o número da porta em que o servidor está rodando
-	log.Printf("Server running on port %s", port)
-
-	// Inicia o servidor HTTP e registra qualquer erro fatal
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	slog.Info("service B listening", "port", cfg.Port)
+
+	// Inicia o servidor HTTP e, ao receber SIGTERM/SIGINT ou se ele falhar, drena as
+	// requisições em andamento e envia os spans pendentes antes de retornar
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	if err := telemetry.RunServer(server, cfg.ShutdownTimeout, tel.Shutdown); err != nil {
+		slog.Error("server error", "error", err)
+		return err
+	}
+	return nil
 }
@@ -55,11 +55,50 @@ type WeatherResponse struct {
 	} `json:"current"`
 }
 
+// Struct para a resposta da API de geocodificação do Open-Meteo
+// Struct to hold the response from the Open-Meteo geocoding API
+type OpenMeteoGeocodingResponse struct {
+	Results []struct {
+		Name      string  `json:"name"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Admin1    string  `json:"admin1"`
+	} `json:"results"`
+}
+
+// Struct para a resposta da API de previsão do Open-Meteo
+// Struct to hold the response from the Open-Meteo forecast API
+type OpenMeteoForecastResponse struct {
+	Current struct {
+		Time          string  `json:"time"`
+		Temperature2m float64 `json:"temperature_2m"`
+	} `json:"current"`
+}
+
+// Struct para a resposta da OpenWeatherMap
+// Struct to hold the response from the OpenWeatherMap API
+type OpenWeatherMapResponse struct {
+	Name string `json:"name"`
+	Main struct {
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Humidity  int     `json:"humidity"`
+	} `json:"main"`
+}
+
+// Temperature is the result of a weather lookup.
+// Temperature é o resultado de uma consulta de clima.
+type Temperature struct {
+	Celsius float64 // Temperature in Celsius
+	Stale   bool    // Served from cache because the upstream failed
+}
+
 type TemperatureResponse struct {
 	Celsius    float64 `json:"temp_C"`
 	Fahrenheit float64 `json:"temp_F"`
 	Kelvin     float64 `json:"temp_K"`
 	City       string  `json:"city"`
+	Stale      bool    `json:"stale,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -95,3 +134,31 @@ type BrasilAPIResponse struct {
 	Street       string `json:"street"`
 	Service      string `json:"service"`
 }
+
+// Struct para a resposta da OpenCEP
+// Struct to hold the response from OpenCEP API
+type OpenCEPResponse struct {
+	CEP         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	UF          string `json:"uf"`
+	IBGE        string `json:"ibge"`
+}
+
+// Struct para a resposta da AwesomeAPI CEP
+// Struct to hold the response from AwesomeAPI CEP
+type AwesomeAPIResponse struct {
+	CEP         string `json:"cep"`
+	AddressType string `json:"address_type"`
+	AddressName string `json:"address_name"`
+	Address     string `json:"address"`
+	State       string `json:"state"`
+	District    string `json:"district"`
+	Lat         string `json:"lat"`
+	Lng         string `json:"lng"`
+	City        string `json:"city"`
+	CityIBGE    string `json:"city_ibge"`
+	DDD         string `json:"ddd"`
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"service-b/breaker"
+	"service-b/models"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// BreakerCEPProvider guards a CEPProvider with a circuit breaker. While the
+// breaker is open the provider answers breaker.ErrOpen at once, which takes it
+// out of the race without waiting for the upstream.
+// BreakerCEPProvider protege um CEPProvider com um disjuntor. Enquanto o
+// disjuntor está aberto o provedor responde breaker.ErrOpen na hora, o que o
+// tira da disputa sem esperar pelo upstream.
+type BreakerCEPProvider struct {
+	CEPProvider                  // Guarded provider
+	Breaker     *breaker.Breaker // Breaker guarding the provider
+}
+
+// NewBreakerCEPProvider wraps provider with a breaker built from settings, whose
+// metrics are recorded with meter. An unknown CEP is a healthy answer and never
+// counts as a failure.
+// Envolve provider com um disjuntor criado a partir de settings, cujas métricas
+// são registradas com meter. Um CEP inexistente é uma resposta saudável e nunca
+// conta como falha.
+func NewBreakerCEPProvider(provider CEPProvider, settings breaker.Settings, meter metric.Meter) *BreakerCEPProvider {
+	settings.IsFailure = func(err error) bool {
+		return err != nil && !errors.Is(err, ErrCEPNotFound)
+	}
+	return &BreakerCEPProvider{
+		CEPProvider: provider,
+		Breaker:     breaker.New(provider.Name(), settings, meter),
+	}
+}
+
+// Fetch resolves the CEP through the guarded provider unless the breaker is open.
+// Resolve o CEP pelo provedor protegido, a menos que o disjuntor esteja aberto.
+func (p *BreakerCEPProvider) Fetch(ctx context.Context, cep string) (models.Location, error) {
+	var location models.Location
+	err := p.Breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		location, err = p.CEPProvider.Fetch(ctx, cep)
+		return err
+	})
+	return location, err
+}
+
+// BreakerWeatherProvider guards a WeatherProvider with a circuit breaker so an
+// open backend fails fast and the next one in the failover order is tried.
+// BreakerWeatherProvider protege um WeatherProvider com um disjuntor para que um
+// backend aberto falhe rápido e o próximo na ordem de failover seja tentado.
+type BreakerWeatherProvider struct {
+	WeatherProvider                  // Guarded provider
+	Breaker         *breaker.Breaker // Breaker guarding the provider
+}
+
+// NewBreakerWeatherProvider wraps provider with a breaker built from settings, whose
+// metrics are recorded with meter.
+// Envolve provider com um disjuntor criado a partir de settings, cujas métricas são
+// registradas com meter.
+func NewBreakerWeatherProvider(provider WeatherProvider, settings breaker.Settings, meter metric.Meter) *BreakerWeatherProvider {
+	return &BreakerWeatherProvider{
+		WeatherProvider: provider,
+		Breaker:         breaker.New(provider.Name(), settings, meter),
+	}
+}
+
+// GetTemperature asks the guarded provider unless the breaker is open.
+// Consulta o provedor protegido, a menos que o disjuntor esteja aberto.
+func (p *BreakerWeatherProvider) GetTemperature(ctx context.Context, city string) (float64, error) {
+	var tempC float64
+	err := p.Breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		tempC, err = p.WeatherProvider.GetTemperature(ctx, city)
+		return err
+	})
+	return tempC, err
+}
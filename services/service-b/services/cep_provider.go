@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-b/models"
+	"sort"
+	"strings"
+)
+
+// DefaultCEPProviders is the provider order used when none is configured.
+// Ordem de provedores usada quando nenhuma é configurada.
+var DefaultCEPProviders = []string{"brasilapi", "viacep"}
+
+// CEPProvider defines a backend able to resolve a CEP into a location.
+// Fetch returns ErrCEPNotFound when the backend knows the CEP does not exist.
+// CEPProvider define um backend capaz de resolver um CEP em uma localização.
+// Fetch retorna ErrCEPNotFound quando o backend sabe que o CEP não existe.
+type CEPProvider interface {
+	Name() string                                                   // Name used in config and error messages.
+	Fetch(ctx context.Context, cep string) (models.Location, error) // Resolve the CEP into a location.
+}
+
+// CEPProviderFactory builds a CEPProvider on top of an APIClient.
+// CEPProviderFactory constrói um CEPProvider a partir de um APIClient.
+type CEPProviderFactory func(client APIClient) CEPProvider
+
+// CEPProviderRegistry keeps the known CEP providers indexed by name.
+// CEPProviderRegistry mantém os provedores de CEP conhecidos indexados por nome.
+type CEPProviderRegistry struct {
+	factories map[string]CEPProviderFactory // Provider factories indexed by name
+}
+
+// NewCEPProviderRegistry creates an empty registry.
+// Cria um registro vazio.
+func NewCEPProviderRegistry() *CEPProviderRegistry {
+	return &CEPProviderRegistry{
+		factories: map[string]CEPProviderFactory{}, // Start without any provider
+	}
+}
+
+// DefaultCEPProviderRegistry creates a registry with every built-in provider.
+// Cria um registro com todos os provedores embutidos.
+func DefaultCEPProviderRegistry() *CEPProviderRegistry {
+	registry := NewCEPProviderRegistry()
+	registry.Register("brasilapi", func(client APIClient) CEPProvider { return &BrasilAPIProvider{Client: client} })
+	registry.Register("viacep", func(client APIClient) CEPProvider { return &ViaCEPProvider{Client: client} })
+	registry.Register("opencep", func(client APIClient) CEPProvider { return &OpenCEPProvider{Client: client} })
+	registry.Register("awesomeapi", func(client APIClient) CEPProvider { return &AwesomeAPIProvider{Client: client} })
+	return registry
+}
+
+// Register adds or replaces a provider factory under the given name.
+// Adiciona ou substitui a fábrica de um provedor com o nome informado.
+func (r *CEPProviderRegistry) Register(name string, factory CEPProviderFactory) {
+	r.factories[strings.ToLower(name)] = factory
+}
+
+// Unregister removes a provider factory from the registry.
+// Remove a fábrica de um provedor do registro.
+func (r *CEPProviderRegistry) Unregister(name string) {
+	delete(r.factories, strings.ToLower(name))
+}
+
+// Names returns the registered provider names in alphabetical order.
+// Retorna os nomes dos provedores registrados em ordem alfabética.
+func (r *CEPProviderRegistry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build instantiates the named providers in the given order.
+// Instancia os provedores informados na ordem recebida.
+func (r *CEPProviderRegistry) Build(names []string, client APIClient) ([]CEPProvider, error) {
+	providers := make([]CEPProvider, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue // Skip blanks and duplicates
+		}
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown CEP provider %q (available: %s)", name, strings.Join(r.Names(), ", "))
+		}
+		seen[name] = true
+		providers = append(providers, factory(client))
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("at least one CEP provider must be configured")
+	}
+	return providers, nil
+}
+
+// fetchCEP performs a GET request against a CEP provider and decodes the JSON body into out.
+// A 404 answer is reported as ErrCEPNotFound.
+// Realiza uma requisição GET a um provedor de CEP e decodifica o corpo JSON em out.
+// Uma resposta 404 é reportada como ErrCEPNotFound.
+func fetchCEP(ctx context.Context, client APIClient, url string, out any) error {
+	err := getJSON(ctx, client, url, out)
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return ErrCEPNotFound
+	}
+	return err
+}
+
+// BrasilAPIProvider fetches location data from the BrasilAPI.
+// Busca dados de localização da API BrasilAPI.
+type BrasilAPIProvider struct {
+	Client APIClient // The API client used for making requests.
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *BrasilAPIProvider) Name() string { return "brasilapi" }
+
+// Fetch resolves the CEP through the BrasilAPI.
+// Resolve o CEP através da BrasilAPI.
+func (p *BrasilAPIProvider) Fetch(ctx context.Context, cep string) (models.Location, error) {
+	var address models.BrasilAPIResponse
+	url := fmt.Sprintf("https://brasilapi.com.br/api/cep/v1/%s", cep) // BrasilAPI URL
+	if err := fetchCEP(ctx, p.Client, url, &address); err != nil {
+		return models.Location{}, err
+	}
+
+	return models.Location{
+		Cep:        &cep,
+		Localidade: &address.Neighborhood,
+		Uf:         &address.State,
+		City:       &address.City,
+	}, nil
+}
+
+// ViaCEPProvider fetches location data from the ViaCEP API.
+// Busca dados de localização da API ViaCEP.
+type ViaCEPProvider struct {
+	Client APIClient // The API client used for making requests.
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *ViaCEPProvider) Name() string { return "viacep" }
+
+// Fetch resolves the CEP through the ViaCEP API.
+// Resolve o CEP através da API ViaCEP.
+func (p *ViaCEPProvider) Fetch(ctx context.Context, cep string) (models.Location, error) {
+	var address models.ViaCEPResponse
+	url := fmt.Sprintf("http://viacep.com.br/ws/%s/json", cep) // ViaCEP URL
+	if err := fetchCEP(ctx, p.Client, url, &address); err != nil {
+		return models.Location{}, err
+	}
+
+	if address.ErrorMessage == "true" {
+		return models.Location{}, ErrCEPNotFound // ViaCEP answers 200 with "erro" for unknown CEPs
+	}
+
+	return models.Location{
+		Cep:        &cep,
+		Localidade: &address.Localidade,
+		Uf:         &address.UF,
+		City:       &address.Localidade,
+	}, nil
+}
+
+// OpenCEPProvider fetches location data from the OpenCEP API.
+// Busca dados de localização da API OpenCEP.
+type OpenCEPProvider struct {
+	Client APIClient // The API client used for making requests.
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *OpenCEPProvider) Name() string { return "opencep" }
+
+// Fetch resolves the CEP through the OpenCEP API.
+// Resolve o CEP através da API OpenCEP.
+func (p *OpenCEPProvider) Fetch(ctx context.Context, cep string) (models.Location, error) {
+	var address models.OpenCEPResponse
+	url := fmt.Sprintf("https://opencep.com/v1/%s", cep) // OpenCEP URL
+	if err := fetchCEP(ctx, p.Client, url, &address); err != nil {
+		return models.Location{}, err
+	}
+
+	return models.Location{
+		Cep:        &cep,
+		Localidade: &address.Localidade,
+		Uf:         &address.UF,
+		City:       &address.Localidade,
+	}, nil
+}
+
+// AwesomeAPIProvider fetches location data from the AwesomeAPI CEP service.
+// Busca dados de localização do serviço de CEP da AwesomeAPI.
+type AwesomeAPIProvider struct {
+	Client APIClient // The API client used for making requests.
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *AwesomeAPIProvider) Name() string { return "awesomeapi" }
+
+// Fetch resolves the CEP through the AwesomeAPI CEP service.
+// Resolve o CEP através do serviço de CEP da AwesomeAPI.
+func (p *AwesomeAPIProvider) Fetch(ctx context.Context, cep string) (models.Location, error) {
+	var address models.AwesomeAPIResponse
+	url := fmt.Sprintf("https://cep.awesomeapi.com.br/json/%s", cep) // AwesomeAPI URL
+	if err := fetchCEP(ctx, p.Client, url, &address); err != nil {
+		return models.Location{}, err
+	}
+
+	return models.Location{
+		Cep:        &cep,
+		Localidade: &address.District,
+		Uf:         &address.State,
+		City:       &address.City,
+	}, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HedgeSettings configures hedged weather lookups.
+// Configura as consultas de clima com hedge.
+type HedgeSettings struct {
+	Percentile   float64       // Latency percentile (0..1) of the primary after which the hedge fires
+	DefaultDelay time.Duration // Delay used until enough latency samples were collected
+	MinDelay     time.Duration // Lower bound for the computed delay
+	MinSamples   int           // Samples needed before the percentile is trusted
+	WindowSize   int           // Number of recent latencies kept
+}
+
+// DefaultHedgeSettings returns the settings used when nothing is configured.
+// Retorna as configurações usadas quando nada é configurado.
+func DefaultHedgeSettings() HedgeSettings {
+	return HedgeSettings{
+		Percentile:   0.95,
+		DefaultDelay: 500 * time.Millisecond,
+		MinDelay:     50 * time.Millisecond,
+		MinSamples:   20,
+		WindowSize:   200,
+	}
+}
+
+// latencyWindow keeps the most recent latencies of a provider.
+// Guarda as latências mais recentes de um provedor.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration // Ring of recent latencies
+	next    int             // Next slot in samples
+	count   int             // Samples recorded, up to len(samples)
+}
+
+// record adds a latency sample.
+// Adiciona uma amostra de latência.
+func (w *latencyWindow) record(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % len(w.samples)
+	w.count = min(w.count+1, len(w.samples))
+}
+
+// percentile returns the p-th percentile of the window and whether there were
+// at least minSamples to compute it from.
+// Retorna o percentil p da janela e se havia ao menos minSamples para calculá-lo.
+func (w *latencyWindow) percentile(p float64, minSamples int) (time.Duration, bool) {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples[:w.count])
+	w.mu.Unlock()
+
+	if len(sorted) == 0 || len(sorted) < minSamples {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	index := int(p * float64(len(sorted)-1))
+	return sorted[index], true
+}
+
+// HedgedWeatherProvider asks the primary backend and, if it has not answered
+// once its usual latency percentile has elapsed, fires the same request at the
+// hedge backend (the primary itself or an alternate). The first good answer wins
+// and the other request is cancelled. Both legs show up as child spans.
+// HedgedWeatherProvider consulta o backend primário e, se ele não responder
+// dentro do percentil usual de latência, dispara a mesma requisição no backend de
+// hedge (o próprio primário ou um alternativo). A primeira resposta válida vence
+// e a outra requisição é cancelada. As duas pernas aparecem como spans filhos.
+type HedgedWeatherProvider struct {
+	primary   WeatherProvider // Backend asked first
+	hedge     WeatherProvider // Backend asked when the primary is slow
+	settings  HedgeSettings   // Hedge thresholds
+	tracer    trace.Tracer    // Starts the span of each leg
+	latencies *latencyWindow  // Recent successful latencies of the primary
+}
+
+// NewHedgedWeatherProvider creates a hedged provider whose legs are traced with
+// tracer; pass the primary as hedge to hedge against the same backend.
+// Cria um provedor com hedge cujas pernas são rastreadas com tracer; passe o
+// primário como hedge para repetir no mesmo backend.
+func NewHedgedWeatherProvider(primary, hedge WeatherProvider, settings HedgeSettings, tracer trace.Tracer) *HedgedWeatherProvider {
+	if settings.WindowSize < 1 {
+		settings.WindowSize = DefaultHedgeSettings().WindowSize
+	}
+	return &HedgedWeatherProvider{
+		primary:   primary,
+		hedge:     hedge,
+		settings:  settings,
+		tracer:    tracer,
+		latencies: &latencyWindow{samples: make([]time.Duration, settings.WindowSize)},
+	}
+}
+
+// Name returns the primary provider name.
+// Retorna o nome do provedor primário.
+func (h *HedgedWeatherProvider) Name() string {
+	return h.primary.Name()
+}
+
+// delay returns how long to wait for the primary before hedging.
+// Retorna quanto esperar pelo primário antes do hedge.
+func (h *HedgedWeatherProvider) delay() time.Duration {
+	delay, ok := h.latencies.percentile(h.settings.Percentile, h.settings.MinSamples)
+	if !ok {
+		return h.settings.DefaultDelay
+	}
+	return max(delay, h.settings.MinDelay)
+}
+
+// hedgeResult is the outcome of one leg of a hedged lookup.
+// Resultado de uma perna de uma consulta com hedge.
+type hedgeResult struct {
+	leg   string
+	tempC float64
+	err   error
+}
+
+// GetTemperature retrieves the current temperature, hedging a slow primary.
+// Recupera a temperatura atual, fazendo hedge de um primário lento.
+func (h *HedgedWeatherProvider) GetTemperature(ctx context.Context, city string) (float64, error) {
+	var wg sync.WaitGroup
+	defer wg.Wait() // No leg outlives the call
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Cancel the losing leg
+
+	span := trace.SpanFromContext(ctx)
+	results := make(chan hedgeResult, 2)
+	run := func(leg string, provider WeatherProvider) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			legCtx, legSpan := h.tracer.Start(ctx, "weather-"+leg, trace.WithAttributes(
+				attribute.String("weather.provider", provider.Name()),
+			))
+			defer legSpan.End()
+
+			start := time.Now()
+			tempC, err := provider.GetTemperature(legCtx, city)
+			if err != nil {
+				legSpan.RecordError(err)
+				legSpan.SetStatus(codes.Error, "weather lookup failed")
+			} else if leg == "primary" {
+				h.latencies.record(time.Since(start))
+			}
+			results <- hedgeResult{leg: leg, tempC: tempC, err: err}
+		}()
+	}
+
+	delay := h.delay()
+	legs := 0
+	fire := func(reason string) {
+		span.AddEvent("weather.hedge", trace.WithAttributes(
+			attribute.String("weather.hedge.provider", h.hedge.Name()),
+			attribute.String("weather.hedge.reason", reason),
+			attribute.Int64("weather.hedge.delay_ms", delay.Milliseconds()),
+		))
+		run("hedge", h.hedge)
+		legs++
+	}
+
+	run("primary", h.primary)
+	legs++
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var errs []error
+	for len(errs) < legs {
+		select {
+		case <-timer.C:
+			if legs == 1 {
+				fire("slow") // Primary is slower than usual
+			}
+		case res := <-results:
+			if res.err == nil {
+				span.SetAttributes(attribute.String("weather.hedge.winner", res.leg))
+				return res.tempC, nil
+			}
+			errs = append(errs, res.err)
+			if legs == 1 && timer.Stop() {
+				fire("failed") // Primary failed before the delay, hedge right away
+			}
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return 0, errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// stubWeatherProvider answers with answer, counting the calls and keeping the
+// error of the context each call ended with.
+// Responde com answer, contando as chamadas e guardando o erro do contexto com
+// que cada chamada terminou.
+type stubWeatherProvider struct {
+	name   string
+	answer func(ctx context.Context) (float64, error)
+	calls  atomic.Int32
+	ended  chan error // Context error of each finished call
+}
+
+func newStubWeatherProvider(name string, answer func(ctx context.Context) (float64, error)) *stubWeatherProvider {
+	return &stubWeatherProvider{name: name, answer: answer, ended: make(chan error, 4)}
+}
+
+func (p *stubWeatherProvider) Name() string { return p.name }
+
+func (p *stubWeatherProvider) GetTemperature(ctx context.Context, _ string) (float64, error) {
+	p.calls.Add(1)
+	defer func() { p.ended <- ctx.Err() }()
+	return p.answer(ctx)
+}
+
+// answerAfter answers celsius after delay, or the context error if it ends first.
+// Responde celsius após delay, ou o erro do contexto se ele terminar antes.
+func answerAfter(delay time.Duration, celsius float64) func(context.Context) (float64, error) {
+	return func(ctx context.Context) (float64, error) {
+		select {
+		case <-time.After(delay):
+			return celsius, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// testHedgeSettings hedges after 50ms until 4 latencies were sampled.
+// Faz hedge após 50ms até que 4 latências tenham sido amostradas.
+func testHedgeSettings() HedgeSettings {
+	return HedgeSettings{
+		Percentile:   0.5,
+		DefaultDelay: 50 * time.Millisecond,
+		MinDelay:     10 * time.Millisecond,
+		MinSamples:   4,
+		WindowSize:   4,
+	}
+}
+
+func newTestHedgedProvider(primary, hedge WeatherProvider) *HedgedWeatherProvider {
+	return NewHedgedWeatherProvider(primary, hedge, testHedgeSettings(), noop.NewTracerProvider().Tracer("test"))
+}
+
+func TestHedgeDelayFollowsThePrimaryLatencyPercentile(t *testing.T) {
+	h := newTestHedgedProvider(newStubWeatherProvider("primary", nil), newStubWeatherProvider("hedge", nil))
+
+	// Until MinSamples latencies were seen the default delay applies
+	for _, latency := range []time.Duration{40, 20, 30} {
+		h.latencies.record(latency * time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, h.delay())
+
+	h.latencies.record(10 * time.Millisecond)
+	assert.Equal(t, 20*time.Millisecond, h.delay(), "median of 10, 20, 30 and 40ms")
+
+	// The window keeps the latest samples only, and never goes below MinDelay
+	for range 4 {
+		h.latencies.record(time.Millisecond)
+	}
+	assert.Equal(t, 10*time.Millisecond, h.delay())
+}
+
+func TestHedgeFiresWhenThePrimaryIsSlow(t *testing.T) {
+	primary := newStubWeatherProvider("primary", answerAfter(time.Second, 20))
+	hedge := newStubWeatherProvider("hedge", answerAfter(0, 25))
+	h := newTestHedgedProvider(primary, hedge)
+
+	start := time.Now()
+	tempC, err := h.GetTemperature(context.Background(), "Recife")
+
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, tempC, "the hedge answered first")
+	assert.GreaterOrEqual(t, time.Since(start), testHedgeSettings().DefaultDelay, "the hedge waits for the delay")
+	assert.ErrorIs(t, <-primary.ended, context.Canceled, "the losing primary is cancelled")
+	assert.NoError(t, <-hedge.ended)
+}
+
+func TestHedgeIsNotFiredForAQuickPrimary(t *testing.T) {
+	primary := newStubWeatherProvider("primary", answerAfter(0, 20))
+	hedge := newStubWeatherProvider("hedge", answerAfter(0, 25))
+	h := newTestHedgedProvider(primary, hedge)
+
+	tempC, err := h.GetTemperature(context.Background(), "Recife")
+
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, tempC)
+	assert.Zero(t, hedge.calls.Load())
+}
+
+func TestHedgeFiresRightAwayWhenThePrimaryFails(t *testing.T) {
+	primary := newStubWeatherProvider("primary", func(context.Context) (float64, error) { return 0, errWeatherDown })
+	hedge := newStubWeatherProvider("hedge", answerAfter(0, 25))
+	h := newTestHedgedProvider(primary, hedge)
+
+	start := time.Now()
+	tempC, err := h.GetTemperature(context.Background(), "Recife")
+
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, tempC)
+	assert.Less(t, time.Since(start), testHedgeSettings().DefaultDelay, "the hedge does not wait for the delay")
+}
+
+func TestHedgeCancelsTheLosingHedge(t *testing.T) {
+	primary := newStubWeatherProvider("primary", answerAfter(80*time.Millisecond, 20))
+	hedge := newStubWeatherProvider("hedge", answerAfter(time.Second, 25))
+	h := newTestHedgedProvider(primary, hedge)
+
+	tempC, err := h.GetTemperature(context.Background(), "Recife")
+
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, tempC, "the primary answered first")
+	assert.EqualValues(t, 1, hedge.calls.Load())
+	assert.ErrorIs(t, <-hedge.ended, context.Canceled, "the losing hedge is cancelled")
+}
+
+func TestHedgeJoinsTheErrorsOfBothLegs(t *testing.T) {
+	errHedgeDown := errors.New("hedge down")
+	primary := newStubWeatherProvider("primary", func(context.Context) (float64, error) { return 0, errWeatherDown })
+	hedge := newStubWeatherProvider("hedge", func(context.Context) (float64, error) { return 0, errHedgeDown })
+	h := newTestHedgedProvider(primary, hedge)
+
+	_, err := h.GetTemperature(context.Background(), "Recife")
+
+	assert.ErrorIs(t, err, errWeatherDown)
+	assert.ErrorIs(t, err, errHedgeDown)
+}
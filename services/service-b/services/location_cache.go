@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"service-b/cache"
+	"service-b/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+// Defaults for the CEP cache, used when nothing is configured.
+// Valores padrão do cache de CEP, usados quando nada é configurado.
+const (
+	DefaultCEPCacheSize        = 10000
+	DefaultCEPCacheTTL         = 24 * time.Hour
+	DefaultCEPCacheNegativeTTL = 10 * time.Minute
+)
+
+// cachedLocation is a cached lookup result; NotFound marks a negative entry.
+// Resultado de consulta em cache; NotFound marca uma entrada negativa.
+type cachedLocation struct {
+	Location models.Location `json:"location"`
+	NotFound bool            `json:"not_found"`
+}
+
+// CachedLocationService wraps a LocationService with a cache.
+// Unknown CEPs are cached too (for negativeTTL) and concurrent lookups of the
+// same CEP share a single upstream call, traced by a span of its own that every
+// caller links to.
+// CachedLocationService envolve um LocationService com um cache.
+// CEPs inexistentes também são guardados (por negativeTTL) e consultas
+// simultâneas do mesmo CEP compartilham uma única chamada externa, rastreada por
+// um span próprio ao qual cada chamador se liga.
+type CachedLocationService struct {
+	next        LocationService    // Service answering cache misses
+	cache       cache.Cache        // Cached lookups indexed by CEP
+	ttl         time.Duration      // Lifetime of found CEPs
+	negativeTTL time.Duration      // Lifetime of unknown CEPs
+	group       singleflight.Group // Collapses concurrent lookups of the same CEP
+	tracer      trace.Tracer       // Starts the span of each shared lookup
+}
+
+// sharedLookup is the outcome of a lookup shared by concurrent callers.
+// Resultado de uma consulta compartilhada por chamadores simultâneos.
+type sharedLookup struct {
+	location models.Location   // Location found by the lookup
+	span     trace.SpanContext // Span of the lookup, linked from each caller
+}
+
+// NewCachedLocationService creates a caching LocationService in front of next whose
+// shared lookups are traced with tracer.
+// Cria um LocationService com cache na frente de next cujas consultas compartilhadas
+// são rastreadas com tracer.
+func NewCachedLocationService(next LocationService, store cache.Cache, ttl, negativeTTL time.Duration, tracer trace.Tracer) LocationService {
+	return &CachedLocationService{
+		next:        next,
+		cache:       store,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		tracer:      tracer,
+	}
+}
+
+// GetLocationFromCEP answers from the cache when possible and records whether it
+// did as attributes on the current span.
+// Responde pelo cache quando possível e registra o resultado como atributos do span atual.
+func (c *CachedLocationService) GetLocationFromCEP(ctx context.Context, cep string) (models.Location, error) {
+	span := trace.SpanFromContext(ctx)
+	key := "cep:" + cep
+
+	var cached cachedLocation
+	if ok := getCached(ctx, c.cache, key, &cached); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true), attribute.Bool("cache.negative", cached.NotFound))
+		metrics.recordCacheLookup(ctx, "cep", true)
+		if cached.NotFound {
+			return models.Location{}, ErrCEPNotFound
+		}
+		return cached.Location, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	metrics.recordCacheLookup(ctx, "cep", false)
+
+	// The shared lookup must not die with the first caller, so it only keeps its values;
+	// the provider race still bounds it with its own timeout. It may outlive the span of
+	// the first caller, so its events and the winner go to a span of its own.
+	// A consulta compartilhada não pode morrer com o primeiro chamador, por isso mantém
+	// apenas os valores do contexto; a disputa de provedores ainda a limita com seu timeout.
+	// Ela pode sobreviver ao span do primeiro chamador, por isso seus eventos e o vencedor
+	// vão para um span próprio.
+	results := c.group.DoChan(cep, func() (any, error) {
+		ctx, lookupSpan := c.tracer.Start(context.WithoutCancel(ctx), "cep-lookup")
+		defer lookupSpan.End()
+
+		location, err := c.next.GetLocationFromCEP(ctx, cep)
+		switch {
+		case err == nil:
+			setCached(ctx, c.cache, key, cachedLocation{Location: location}, c.ttl)
+		case errors.Is(err, ErrCEPNotFound):
+			setCached(ctx, c.cache, key, cachedLocation{NotFound: true}, c.negativeTTL)
+		default:
+			lookupSpan.RecordError(err)
+			lookupSpan.SetStatus(codes.Error, "cep lookup failed")
+		}
+		return sharedLookup{location: location, span: lookupSpan.SpanContext()}, err
+	})
+
+	select {
+	case res := <-results:
+		lookup := res.Val.(sharedLookup)
+		span.SetAttributes(attribute.Bool("cache.shared", res.Shared))
+		span.AddLink(trace.Link{SpanContext: lookup.span})
+		return lookup.location, res.Err
+	case <-ctx.Done():
+		return models.Location{}, ctx.Err() // Client went away while waiting for the lookup
+	}
+}
+
+// getCached reads and decodes key into out. Backend and decoding failures are
+// recorded on the current span and reported as a miss.
+// Lê e decodifica key em out. Falhas do backend e da decodificação são
+// registradas no span atual e tratadas como ausência.
+func getCached(ctx context.Context, store cache.Cache, key string, out any) bool {
+	payload, ok, err := store.Get(ctx, key)
+	if err == nil && ok {
+		err = cache.Decode(payload, out)
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err, trace.WithAttributes(attribute.String("cache.key", key)))
+		return false
+	}
+	return ok
+}
+
+// setCached encodes and stores value under key. Failures are recorded on the
+// current span, a cache that cannot be written must not fail the request.
+// Codifica e guarda value em key. Falhas são registradas no span atual, um
+// cache que não pode ser gravado não deve falhar a requisição.
+func setCached(ctx context.Context, store cache.Cache, key string, value any, ttl time.Duration) {
+	payload, err := cache.Encode(value)
+	if err == nil {
+		err = store.Set(ctx, key, payload, ttl)
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err, trace.WithAttributes(attribute.String("cache.key", key)))
+	}
+}
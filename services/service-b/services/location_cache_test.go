@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"service-b/cache"
+	"service-b/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var errLookupDown = errors.New("lookup down")
+
+// stubLocationService answers with answer, counting the lookups of each CEP.
+// Responde com answer, contando as consultas de cada CEP.
+type stubLocationService struct {
+	mu     sync.Mutex
+	calls  map[string]int
+	answer func(ctx context.Context, cep string) (models.Location, error)
+}
+
+func newStubLocationService(answer func(ctx context.Context, cep string) (models.Location, error)) *stubLocationService {
+	return &stubLocationService{calls: map[string]int{}, answer: answer}
+}
+
+func (s *stubLocationService) GetLocationFromCEP(ctx context.Context, cep string) (models.Location, error) {
+	s.mu.Lock()
+	s.calls[cep]++
+	s.mu.Unlock()
+	return s.answer(ctx, cep)
+}
+
+func (s *stubLocationService) lookups(cep string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[cep]
+}
+
+// locationOf answers with a location named after the CEP.
+// Responde com uma localidade nomeada pelo CEP.
+func locationOf(_ context.Context, cep string) (models.Location, error) {
+	return models.Location{Localidade: &cep}, nil
+}
+
+func newTestLocationCache(next LocationService, size int, negativeTTL time.Duration) LocationService {
+	return NewCachedLocationService(next, cache.NewMemory(size), time.Hour, negativeTTL, noop.NewTracerProvider().Tracer("test"))
+}
+
+func TestLocationCacheEvictsTheLeastRecentlyUsedCEP(t *testing.T) {
+	upstream := newStubLocationService(locationOf)
+	c := newTestLocationCache(upstream, 2, time.Hour)
+
+	for _, cep := range []string{"01001000", "20040002", "01001000", "30130010"} {
+		location, err := c.GetLocationFromCEP(context.Background(), cep)
+		require.NoError(t, err)
+		assert.Equal(t, cep, *location.Localidade)
+	}
+	// 20040002 was the least recently used when 30130010 came in
+	for _, cep := range []string{"01001000", "30130010", "20040002"} {
+		_, err := c.GetLocationFromCEP(context.Background(), cep)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, upstream.lookups("01001000"), "kept by its recent use")
+	assert.Equal(t, 1, upstream.lookups("30130010"))
+	assert.Equal(t, 2, upstream.lookups("20040002"), "evicted and looked up again")
+}
+
+func TestLocationCacheRemembersUnknownCEPs(t *testing.T) {
+	upstream := newStubLocationService(func(context.Context, string) (models.Location, error) {
+		return models.Location{}, ErrCEPNotFound
+	})
+	c := newTestLocationCache(upstream, 10, 20*time.Millisecond)
+
+	for range 3 {
+		_, err := c.GetLocationFromCEP(context.Background(), "99999999")
+		assert.ErrorIs(t, err, ErrCEPNotFound)
+	}
+	assert.Equal(t, 1, upstream.lookups("99999999"))
+
+	// The negative entry lives for negativeTTL only
+	time.Sleep(30 * time.Millisecond)
+	_, err := c.GetLocationFromCEP(context.Background(), "99999999")
+	assert.ErrorIs(t, err, ErrCEPNotFound)
+	assert.Equal(t, 2, upstream.lookups("99999999"))
+}
+
+func TestLocationCacheDoesNotCacheOutages(t *testing.T) {
+	upstream := newStubLocationService(func(context.Context, string) (models.Location, error) {
+		return models.Location{}, errLookupDown
+	})
+	c := newTestLocationCache(upstream, 10, time.Hour)
+
+	for range 2 {
+		_, err := c.GetLocationFromCEP(context.Background(), "01001000")
+		assert.ErrorIs(t, err, errLookupDown)
+	}
+
+	assert.Equal(t, 2, upstream.lookups("01001000"))
+}
+
+func TestLocationCacheSharesConcurrentLookups(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	const callers = 4
+	release, started := make(chan struct{}), make(chan struct{})
+	var fetches atomic.Int32
+	winner := newStubCEPProvider("winner", func(context.Context) (models.Location, error) {
+		if fetches.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		city := "São Paulo"
+		return models.Location{Localidade: &city}, nil
+	})
+	winner.ctxErr = make(chan error, callers) // Room for every caller should the lookup not be shared
+	c := NewCachedLocationService(NewLocationService([]CEPProvider{winner}), cache.NewMemory(10), time.Hour, time.Hour, tracer)
+
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, span := tracer.Start(context.Background(), "caller")
+			defer span.End()
+			location, err := c.GetLocationFromCEP(ctx, "01001000")
+			assert.NoError(t, err)
+			assert.Equal(t, "São Paulo", *location.Localidade)
+		}()
+	}
+	<-started
+	time.Sleep(20 * time.Millisecond) // Let the other callers join the running lookup
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, fetches.Load(), "a single upstream lookup for every caller")
+
+	// Each caller links to the span of the shared lookup, which names the winner
+	var lookups, callerSpans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "cep-lookup" {
+			lookups = append(lookups, span)
+		} else {
+			callerSpans = append(callerSpans, span)
+		}
+	}
+	require.Len(t, lookups, 1)
+	assert.Contains(t, lookups[0].Attributes(), attribute.String("provider.winner", "winner"))
+	require.Len(t, callerSpans, callers)
+	for _, span := range callerSpans {
+		assert.Contains(t, span.Attributes(), attribute.Bool("cache.shared", true))
+		require.Len(t, span.Links(), 1)
+		assert.Equal(t, lookups[0].SpanContext().SpanID(), span.Links()[0].SpanContext.SpanID())
+	}
+}
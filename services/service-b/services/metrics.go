@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// serviceMetrics holds the instruments recorded by the lookup services.
+// Instruments come from the global MeterProvider and are no-ops until one is set.
+// Guarda os instrumentos registrados pelos serviços de consulta.
+// Os instrumentos vêm do MeterProvider global e não fazem nada até que um seja definido.
+type serviceMetrics struct {
+	upstreamDuration metric.Float64Histogram // Latency of each upstream attempt, by host and outcome
+	cepWins          metric.Int64Counter     // CEP races won, by provider
+	cacheLookups     metric.Int64Counter     // Cache lookups, by cache and hit
+}
+
+var metrics = newServiceMetrics()
+
+func newServiceMetrics() serviceMetrics {
+	meter := otel.Meter("service-b/services")
+	upstreamDuration, _ := meter.Float64Histogram("upstream.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of each request attempt sent to an upstream API"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10))
+	cepWins, _ := meter.Int64Counter("cep.provider.wins",
+		metric.WithDescription("CEP provider races won, by provider"))
+	cacheLookups, _ := meter.Int64Counter("cache.lookups",
+		metric.WithDescription("Cache lookups, the hit ratio is hits over the total"))
+	return serviceMetrics{
+		upstreamDuration: upstreamDuration,
+		cepWins:          cepWins,
+		cacheLookups:     cacheLookups,
+	}
+}
+
+// recordUpstream records the latency of an upstream attempt.
+// Registra a latência de uma tentativa a um upstream.
+func (m serviceMetrics) recordUpstream(ctx context.Context, req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	attrs := []attribute.KeyValue{attribute.String("server.address", req.URL.Hostname())}
+	switch {
+	case err != nil:
+		attrs = append(attrs, attribute.String("error.type", "transport"))
+	default:
+		attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	m.upstreamDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+}
+
+// recordCEPWin counts the provider that won a CEP race.
+// Conta o provedor que venceu uma disputa de CEP.
+func (m serviceMetrics) recordCEPWin(ctx context.Context, provider string) {
+	m.cepWins.Add(ctx, 1, metric.WithAttributes(attribute.String("provider", provider)))
+}
+
+// recordCacheLookup counts a lookup in the named cache.
+// Conta uma consulta ao cache informado.
+func (m serviceMetrics) recordCacheLookup(ctx context.Context, cache string, hit bool) {
+	m.cacheLookups.Add(ctx, 1, metric.WithAttributes(attribute.String("cache.name", cache), attribute.Bool("cache.hit", hit)))
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy describes how an idempotent upstream call is retried.
+// RetryPolicy descreve como uma chamada idempotente a um upstream é repetida.
+type RetryPolicy struct {
+	MaxAttempts          int           // Total attempts, 1 disables retries
+	InitialBackoff       time.Duration // Delay before the second attempt
+	MaxBackoff           time.Duration // Upper bound for the computed delay
+	Multiplier           float64       // Growth of the delay between attempts
+	Jitter               float64       // Fraction of the delay drawn at random, 0 disables it
+	RetryableStatusCodes []int         // Statuses worth another attempt
+}
+
+// DefaultRetryPolicy returns the policy used for upstreams without their own.
+// Retorna a política usada para upstreams sem uma própria.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// retryReason tells why an attempt should be retried, or "" when it should not.
+// Informa por que uma tentativa deve ser repetida, ou "" quando não deve.
+func (p RetryPolicy) retryReason(ctx context.Context, resp *http.Response, err error) string {
+	if ctx.Err() != nil {
+		return "" // The caller gave up
+	}
+	if err != nil {
+		return "error"
+	}
+	if slices.Contains(p.RetryableStatusCodes, resp.StatusCode) {
+		return "status " + strconv.Itoa(resp.StatusCode)
+	}
+	return ""
+}
+
+// backoff returns the delay before the next attempt: exponential with jitter,
+// or the upstream's Retry-After when it sent one. A Retry-After beyond MaxBackoff
+// is not honoured and ok is false: the caller gives up instead of parking the
+// request, and retrying earlier would only be refused again.
+// Retorna a espera antes da próxima tentativa: exponencial com jitter, ou o
+// Retry-After do upstream quando ele envia um. Um Retry-After acima de MaxBackoff
+// não é respeitado e ok é false: quem chama desiste em vez de prender a requisição,
+// e repetir antes só seria recusado de novo.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) (delay time.Duration, ok bool) {
+	if resp != nil {
+		if retryAfter, found := parseRetryAfter(resp.Header.Get("Retry-After")); found {
+			return retryAfter, p.MaxBackoff <= 0 || retryAfter <= p.MaxBackoff
+		}
+	}
+
+	exponential := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		exponential = math.Min(exponential, float64(p.MaxBackoff))
+	}
+	// Part fixed, part random (half and half by default), so replicas do not retry in lockstep
+	// Parte fixa, parte aleatória (metade de cada por padrão), para que réplicas não repitam em sincronia
+	return time.Duration(exponential*(1-p.Jitter) + rand.Float64()*exponential*p.Jitter), true
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or HTTP date form.
+// Lê um cabeçalho Retry-After em segundos ou no formato de data HTTP.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// retryPolicyFor returns the retry policy configured for an upstream host.
+// Retorna a política de repetição configurada para um host externo.
+func (api *APIClientImpl) retryPolicyFor(host string) RetryPolicy {
+	if policy, ok := api.RetryPolicies[strings.ToLower(host)]; ok {
+		return policy
+	}
+	return api.DefaultRetryPolicy
+}
+
+// doWithRetry sends req following the upstream retry policy. Every retry is
+// recorded as an event on the current span, and no retry is attempted when its
+// delay would overrun the request deadline.
+// Envia req seguindo a política de repetição do upstream. Cada repetição é
+// registrada como evento no span atual, e nenhuma repetição é feita quando a
+// espera ultrapassaria o prazo da requisição.
+func (api *APIClientImpl) doWithRetry(ctx context.Context, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	policy := api.retryPolicyFor(req.URL.Hostname())
+	span := trace.SpanFromContext(ctx)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := api.do(ctx, req, timeout)
+
+		reason := policy.retryReason(ctx, resp, err)
+		if attempt >= policy.MaxAttempts || reason == "" {
+			return resp, err
+		}
+		delay, ok := policy.backoff(attempt, resp)
+		if !ok {
+			return resp, err // The upstream asked to wait longer than MaxBackoff
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err // Retrying would not fit in the request deadline
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body) // Drain so the connection can be reused
+			resp.Body.Close()
+		}
+
+		span.AddEvent("http.retry", trace.WithAttributes(
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.Int("http.request.resend_count", attempt),
+			attribute.String("retry.reason", reason),
+			attribute.Int64("retry.delay_ms", delay.Milliseconds()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
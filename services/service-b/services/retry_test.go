@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffHonoursShortRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"1"}}}
+
+	delay, ok := DefaultRetryPolicy().backoff(1, resp)
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+}
+
+func TestRetryAfterBeyondMaxBackoffGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := NewAPIClient(&http.Client{}, nil, time.Second)
+
+	start := time.Now()
+	resp, err := client.Get(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.EqualValues(t, 1, attempts.Load(), "no retry after an hour-long Retry-After")
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -1,189 +1,375 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"service-b/models"
+	"strings"
+	"sync"
+	"telemetry"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // APIClient defines the behavior of an external API client.
 // APIClient define o comportamento de um cliente para consumir APIs externas.
 type APIClient interface {
-	Get(url string) (*http.Response, error)
+	Get(ctx context.Context, url string, opts ...RequestOption) (*http.Response, error)
+}
+
+// requestOptions holds the per-request settings applied by RequestOption.
+// Guarda as configurações por requisição aplicadas por RequestOption.
+type requestOptions struct {
+	headers http.Header   // Extra headers sent with the request
+	timeout time.Duration // Deadline for the whole request, body included
+}
+
+// DefaultUpstreamTimeout bounds upstream calls when no per-host timeout is configured.
+// Limita as chamadas externas quando nenhum timeout por host é configurado.
+const DefaultUpstreamTimeout = 5 * time.Second
+
+// RequestOption customizes a single APIClient request.
+// RequestOption personaliza uma única requisição do APIClient.
+type RequestOption func(*requestOptions)
+
+// WithHeader adds a header to the request.
+// Adiciona um cabeçalho à requisição.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.headers.Add(key, value)
+	}
+}
+
+// WithTimeout overrides the upstream timeout for the request.
+// Sobrescreve o timeout do upstream para a requisição.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
 }
 
 // LocationService is an interface that defines the methods to interact with location services.
 // LocationService é uma interface que define os métodos para interagir com serviços de localização.
 type LocationService interface {
-	GetLocationFromCEP(cep string, chBrasilAPI, chViaCEP chan models.Location) (models.Location, error)
+	GetLocationFromCEP(ctx context.Context, cep string) (models.Location, error)
 }
 
+// Errors returned by LocationService so callers can tell a missing CEP apart from an outage.
+// Erros retornados pelo LocationService para diferenciar CEP inexistente de indisponibilidade.
+var (
+	ErrCEPNotFound             = errors.New("can not find zipcode")          // Every provider reported the CEP as unknown
+	ErrCEPProvidersUnavailable = errors.New("zipcode providers unavailable") // No provider gave a definitive answer
+)
+
 // WeatherService is an interface that defines the methods for interacting with weather services.
 // WeatherService é uma interface que define os métodos para interagir com serviços de clima.
 type WeatherService interface {
-	GetTemperature(city string) (float64, error) // Get the temperature for a given city.
-	GetClient() APIClient                        // Return the API client used by the service.
+	GetTemperature(ctx context.Context, city, uf string) (models.Temperature, error) // Get the temperature for a given city.
 }
 
 // WeatherServiceImpl is the concrete implementation of the WeatherService interface.
 // WeatherServiceImpl é a implementação concreta da interface WeatherService.
 type WeatherServiceImpl struct {
-	Client APIClient // The API client used for making requests.
+	Providers []WeatherProvider // Weather backends tried in configured failover order
 }
 
 // APIClientImpl is the concrete implementation of the APIClient interface.
 // APIClientImpl é a implementação concreta da interface APIClient.
 type APIClientImpl struct {
-	Client *http.Client // The HTTP client used for making GET requests.
+	Client         *http.Client             // The HTTP client used for making GET requests.
+	Timeouts       map[string]time.Duration // Timeouts indexed by upstream host
+	DefaultTimeout time.Duration            // Timeout for hosts without an entry in Timeouts, zero disables it
+
+	RetryPolicies      map[string]RetryPolicy // Retry policies indexed by upstream host
+	DefaultRetryPolicy RetryPolicy            // Policy for hosts without an entry in RetryPolicies
 }
 
 // LocationServiceImpl is the concrete implementation of the LocationService interface.
 // LocationServiceImpl é a implementação concreta da interface LocationService.
 type LocationServiceImpl struct {
-	WeatherService WeatherService // Weather service instance to interact with weather data
+	Providers []CEPProvider // CEP providers taking part in the race, in configured order
 }
 
 // NewWeatherService creates and returns a new instance of WeatherServiceImpl.
 // Cria e retorna uma nova instância do WeatherServiceImpl.
-func NewWeatherService(client APIClient) WeatherService {
+func NewWeatherService(providers []WeatherProvider) WeatherService {
 	return &WeatherServiceImpl{
-		Client: client, // Assign the provided API client
+		Providers: providers, // Assign the configured weather backends
 	}
 }
 
 // NewLocationService creates and returns a new LocationServiceImpl instance.
 // Cria e retorna uma nova instância do LocationServiceImpl.
-func NewLocationService(weatherService WeatherService) LocationService {
+func NewLocationService(providers []CEPProvider) LocationService {
 	return &LocationServiceImpl{
-		WeatherService: weatherService, // Assign the provided weather service
+		Providers: providers, // Assign the configured CEP providers
 	}
 }
 
 // NewAPIClient creates and returns a new instance of APIClientImpl.
+// The client transport is wrapped with OpenTelemetry instrumentation so every
+// upstream call becomes a child span and carries the trace context.
 // Cria e retorna uma nova instância do APIClientImpl.
-func NewAPIClient(client *http.Client) *APIClientImpl {
+// O transporte do cliente é instrumentado com OpenTelemetry para que cada chamada
+// externa vire um span filho e propague o contexto do trace.
+func NewAPIClient(client *http.Client, timeouts map[string]time.Duration, defaultTimeout time.Duration) *APIClientImpl {
+	client.Transport = telemetry.NewTransport(client.Transport) // Spans named e.g. "GET viacep.com.br"
+
 	return &APIClientImpl{
-		Client: client, // Initialize the HTTP client
+		Client:             client,         // Initialize the HTTP client
+		Timeouts:           timeouts,       // Per-upstream timeouts
+		DefaultTimeout:     defaultTimeout, // Fallback timeout
+		RetryPolicies:      map[string]RetryPolicy{},
+		DefaultRetryPolicy: DefaultRetryPolicy(), // Fallback retry policy
 	}
 }
 
+// ParseUpstreamTimeouts parses a "host=duration" comma separated list,
+// e.g. "viacep.com.br=2s,api.weatherapi.com=3s".
+// Interpreta uma lista "host=duração" separada por vírgulas.
+func ParseUpstreamTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, duration, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid upstream timeout %q, expected host=duration", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream timeout %q: %w", entry, err)
+		}
+		timeouts[strings.ToLower(strings.TrimSpace(host))] = timeout
+	}
+	return timeouts, nil
+}
+
+// lookupTimeout bounds a whole CEP race or weather failover, retries included.
+// Limita uma disputa de CEP ou um failover de clima inteiro, repetições incluídas.
+const lookupTimeout = 10 * time.Second
+
 // GetTemperature retrieves the current temperature for a given city.
+// Backends are tried in configured order and the first successful answer is returned,
+// all within lookupTimeout.
+// The UF only tells homonymous cities apart in caches, backends look up the city name.
 // Recupera a temperatura atual para uma cidade específica.
-func (ws *WeatherServiceImpl) GetTemperature(city string) (float64, error) {
-	apiKey := os.Getenv("WEATHER_API_KEY") // Retrieve API key from environment variable
-	// Fix spaces on names
-	encodedCity := url.QueryEscape(city) // Encode the city name to ensure it works in a URL
-	url := fmt.Sprintf("https://api.weatherapi.com/v1/current.json?key=%s&q=%s", apiKey, encodedCity)
+// Os backends são tentados na ordem configurada e a primeira resposta válida é retornada,
+// tudo dentro de lookupTimeout.
+// A UF apenas diferencia cidades homônimas nos caches, os backends consultam pelo nome.
+func (ws *WeatherServiceImpl) GetTemperature(ctx context.Context, city, uf string) (models.Temperature, error) {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout) // Bound retries and failover as a whole
+	defer cancel()
 
-	resp, err := ws.Client.Get(url) // Send GET request to the weather API
-	if err != nil {
-		return 0, err // Return error if the request fails
-	}
-	defer resp.Body.Close() // Close response body when done
-
-	var weather models.WeatherResponse
-	if err := json.NewDecoder(resp.Body).Decode(&weather); err != nil {
-		return 0, err // Return error if the response cannot be decoded
+	var errs []error
+	for _, provider := range ws.Providers {
+		tempC, err := provider.GetTemperature(ctx, city)
+		if err == nil {
+			return models.Temperature{Celsius: tempC}, nil // Return the temperature in Celsius
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		if ctx.Err() != nil {
+			break // The request is over, there is no point in failing over
+		}
 	}
-
-	return weather.Current.TempC, nil // Return the temperature in Celsius
+	return models.Temperature{}, fmt.Errorf("failed to get temperature: %w", errors.Join(errs...))
 }
 
-// GetClient returns the APIClient used in WeatherServiceImpl.
-// Retorna o APIClient usado no WeatherServiceImpl.
-func (ws *WeatherServiceImpl) GetClient() APIClient {
-	return ws.Client // Return the client used for HTTP requests
+// cepResult is the outcome of a single provider in the race.
+// Resultado de um único provedor na disputa.
+type cepResult struct {
+	provider string          // Name of the provider
+	location models.Location // Location found by the provider
+	err      error           // Failure reported by the provider
 }
 
 // GetLocationFromCEP retrieves location data based on a given CEP.
+// Every provider is queried concurrently and the first successful reply wins, named
+// by the provider.winner attribute of the current span; a failing provider only falls
+// back to the others. The losing requests are cancelled and every goroutine has
+// returned before this method does.
 // Recupera dados de localização com base em um CEP fornecido.
-func (ls *LocationServiceImpl) GetLocationFromCEP(cep string, chBrasilAPI, chViaCEP chan models.Location) (models.Location, error) {
-	timeout := time.After(10 * time.Second) // Set a timeout for the operation
-	// Asynchronously fetch data from the APIs
-	// Busca os dados de forma assíncrona das APIs
-	go ls.fetchFromBrasilAPI(cep, chBrasilAPI)
-	go ls.fetchFromViaCEP(cep, chViaCEP)
-
-	select {
-	case res := <-chBrasilAPI: // Handle response from BrasilAPI
-		if res.Localidade != nil {
-			return res, nil // Return location data if valid
+// Todos os provedores são consultados em paralelo e a primeira resposta válida vence,
+// indicada pelo atributo provider.winner do span atual; a falha de um provedor apenas
+// recorre aos demais. As requisições perdedoras são
+// canceladas e as goroutines terminam antes do retorno.
+func (ls *LocationServiceImpl) GetLocationFromCEP(ctx context.Context, cep string) (models.Location, error) {
+	var wg sync.WaitGroup
+	defer wg.Wait() // Wait for every provider to return so no goroutine outlives the call
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout) // Set a timeout for the operation
+	defer cancel()                                         // Cancel the losing providers' requests
+
+	// Results are buffered so a provider never blocks once the race is over
+	// Resultados são bufferizados para que um provedor nunca bloqueie após a disputa
+	results := make(chan cepResult, len(ls.Providers))
+
+	// Asynchronously fetch data from the APIs, in configured order
+	// Busca os dados de forma assíncrona das APIs, na ordem configurada
+	for _, provider := range ls.Providers {
+		wg.Add(1)
+		go func(provider CEPProvider) {
+			defer wg.Done()
+			location, err := provider.Fetch(ctx, cep)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", provider.Name(), err)
+			}
+			results <- cepResult{provider: provider.Name(), location: location, err: err}
+		}(provider)
+	}
+
+	var errs []error
+	for len(errs) < len(ls.Providers) {
+		select {
+		case res := <-results:
+			if res.err == nil {
+				metrics.recordCEPWin(ctx, res.provider)
+				trace.SpanFromContext(ctx).SetAttributes(attribute.String("provider.winner", res.provider))
+				return res.location, nil // First successful provider wins
+			}
+			errs = append(errs, res.err) // A provider failed, keep waiting for the others
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// Return timeout error
+				return models.Location{}, fmt.Errorf("%w: timeout after %s", ErrCEPProvidersUnavailable, lookupTimeout)
+			}
+			return models.Location{}, ctx.Err() // Client went away before any provider replied
 		}
-		return models.Location{}, errors.New("error searching for CEP data")
-	case res := <-chViaCEP: // Handle response from ViaCEP
-		if res.Localidade != nil {
-			return res, nil // Return location data if valid
+	}
+
+	return models.Location{}, raceError(errs)
+}
+
+// raceError summarizes the failures of every provider: the CEP is only reported
+// as not found when all providers agree, otherwise the lookup is unavailable.
+// Resume as falhas de todos os provedores: o CEP só é dado como inexistente quando
+// todos concordam, caso contrário a consulta é considerada indisponível.
+func raceError(errs []error) error {
+	for _, err := range errs {
+		if !errors.Is(err, ErrCEPNotFound) {
+			return fmt.Errorf("%w: %w", ErrCEPProvidersUnavailable, errors.Join(errs...))
 		}
-		return models.Location{}, errors.New("error searching for CEP data")
-	case <-timeout: // Timeout after 10 seconds
-		return models.Location{}, errors.New("timeout after 10 seconds") // Return timeout error
 	}
+	return ErrCEPNotFound
 }
 
-// fetchFromBrasilAPI fetches location data from the BrasilAPI.
-// Busca dados de localização da API BrasilAPI.
-func (ls *LocationServiceImpl) fetchFromBrasilAPI(cep string, ch chan models.Location) {
-	url := fmt.Sprintf("https://brasilapi.com.br/api/cep/v1/%s", cep) // BrasilAPI URL
-	resp, err := ls.WeatherService.GetClient().Get(url)               // Use GetClient to avoid casting
-	if err != nil || resp.StatusCode != http.StatusOK {
-		ch <- models.Location{} // Send empty location if error occurs
-		return
+// HTTPStatusError reports an unexpected HTTP status returned by an upstream API.
+// HTTPStatusError reporta um status HTTP inesperado retornado por uma API externa.
+type HTTPStatusError struct {
+	StatusCode int // Status code returned by the upstream API
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// getJSON performs a GET request and decodes a JSON body into out.
+// Any status other than 200 is reported as an *HTTPStatusError.
+// Realiza uma requisição GET e decodifica o corpo JSON em out.
+// Qualquer status diferente de 200 é reportado como *HTTPStatusError.
+func getJSON(ctx context.Context, client APIClient, url string, out any) error {
+	resp, err := client.Get(ctx, url, WithHeader("Accept", "application/json"))
+	if err != nil {
+		return err // Return error if the request fails
 	}
 	defer resp.Body.Close() // Close response body when done
 
-	var address models.BrasilAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&address); err != nil {
-		ch <- models.Location{} // Send empty location if decoding fails
-		return
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPStatusError{StatusCode: resp.StatusCode}
 	}
 
-	// Send location data to the channel
-	ch <- models.Location{
-		Cep:        &cep,
-		Localidade: &address.Neighborhood,
-		Uf:         &address.State,
-		City:       &address.City,
+	return json.NewDecoder(resp.Body).Decode(out) // Return error if the response cannot be decoded
+}
+
+// withoutURL replaces the URL in a *url.Error with its host: the query string may
+// carry an API key, and these errors are logged and recorded on spans. The
+// underlying error is kept for errors.Is, e.g. context.DeadlineExceeded.
+// Substitui a URL de um *url.Error pelo seu host: a query string pode conter uma
+// chave de API, e esses erros são registrados em logs e spans. O erro de origem é
+// mantido para errors.Is, ex.: context.DeadlineExceeded.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
 	}
+	host := "upstream"
+	if parsed, parseErr := url.Parse(urlErr.URL); parseErr == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	return fmt.Errorf("%s %s: %w", urlErr.Op, host, urlErr.Err)
 }
 
-// fetchFromViaCEP fetches location data from the ViaCEP API.
-// Busca dados de localização da API ViaCEP.
-func (ls *LocationServiceImpl) fetchFromViaCEP(cep string, ch chan models.Location) {
-	url := fmt.Sprintf("http://viacep.com.br/ws/%s/json", cep) // ViaCEP URL
-	resp, err := ls.WeatherService.GetClient().Get(url)        // Use GetClient to avoid casting
-	if err != nil || resp.StatusCode != http.StatusOK {
-		ch <- models.Location{} // Send empty location if error occurs
-		return
+// timeoutFor returns the timeout configured for an upstream host.
+// Retorna o timeout configurado para um host externo.
+func (api *APIClientImpl) timeoutFor(host string) time.Duration {
+	if timeout, ok := api.Timeouts[strings.ToLower(host)]; ok {
+		return timeout
 	}
-	defer resp.Body.Close() // Close response body when done
+	return api.DefaultTimeout
+}
+
+// cancelOnClose releases the request deadline once the body has been consumed.
+// Libera o prazo da requisição quando o corpo termina de ser consumido.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
 
-	var address models.ViaCEPResponse
-	if err := json.NewDecoder(resp.Body).Decode(&address); err != nil {
-		ch <- models.Location{} // Send empty location if decoding fails
-		return
+// Get performs an HTTP GET request bound to the given context, applying the
+// upstream timeout, retry policy and any request options.
+// Realiza uma requisição HTTP GET vinculada ao contexto informado, aplicando o
+// timeout e a política de repetição do upstream e as opções da requisição.
+func (api *APIClientImpl) Get(ctx context.Context, url string, opts ...RequestOption) (*http.Response, error) {
+	options := requestOptions{headers: http.Header{}}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	if address.ErrorMessage == "true" {
-		ch <- models.Location{} // Send empty location if decoding fails
-		return
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, withoutURL(err)
+	}
+	for key, values := range options.headers {
+		req.Header[key] = values
 	}
 
-	// Send location data to the channel
-	ch <- models.Location{
-		Cep:        &cep,
-		Localidade: &address.Localidade,
-		Uf:         &address.UF,
-		City:       &address.Localidade,
+	timeout := options.timeout
+	if timeout == 0 {
+		timeout = api.timeoutFor(req.URL.Hostname())
 	}
+
+	return api.doWithRetry(ctx, req, timeout)
 }
 
-// Get performs an HTTP GET request.
-// Realiza uma requisição HTTP GET.
-func (api *APIClientImpl) Get(url string) (*http.Response, error) {
-	return api.Client.Get(url) // Perform the GET request using the HTTP client
+// do sends a single attempt of req, bounded by the upstream timeout.
+// Envia uma única tentativa de req, limitada pelo timeout do upstream.
+func (api *APIClientImpl) do(ctx context.Context, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	start := time.Now()
+	resp, err := api.Client.Do(req.Clone(ctx)) // Perform the GET request using the HTTP client
+	metrics.recordUpstream(ctx, req, resp, err, time.Since(start))
+	if err != nil {
+		cancel()
+		return nil, withoutURL(err)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
 }
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"service-b/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCEPProvider answers with fetch, recording the context error it returns with.
+// Responde com fetch, registrando o erro do contexto com que retornou.
+type stubCEPProvider struct {
+	name   string
+	fetch  func(ctx context.Context) (models.Location, error)
+	ctxErr chan error // Receives ctx.Err() once fetch returns
+}
+
+func newStubCEPProvider(name string, fetch func(ctx context.Context) (models.Location, error)) *stubCEPProvider {
+	return &stubCEPProvider{name: name, fetch: fetch, ctxErr: make(chan error, 1)}
+}
+
+func (p *stubCEPProvider) Name() string { return p.name }
+
+func (p *stubCEPProvider) Fetch(ctx context.Context, _ string) (models.Location, error) {
+	location, err := p.fetch(ctx)
+	p.ctxErr <- ctx.Err()
+	return location, err
+}
+
+// blockUntilCancelled never answers before its context is cancelled.
+// Nunca responde antes de seu contexto ser cancelado.
+func blockUntilCancelled(ctx context.Context) (models.Location, error) {
+	<-ctx.Done()
+	return models.Location{}, ctx.Err()
+}
+
+// assertNoLeakedGoroutines waits for the goroutine count to go back to baseline.
+// Espera o número de goroutines voltar ao valor de referência.
+func assertNoLeakedGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+	// Polled from the test goroutine itself, assert.Eventually would add its own
+	// Verificado na própria goroutine do teste, assert.Eventually criaria a sua
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline, "goroutines leaked")
+}
+
+func TestGetLocationFromCEPCancelsLosers(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	city := "São Paulo"
+	winner := newStubCEPProvider("winner", func(context.Context) (models.Location, error) {
+		return models.Location{Localidade: &city}, nil
+	})
+	loser := newStubCEPProvider("loser", blockUntilCancelled)
+	service := NewLocationService([]CEPProvider{loser, winner})
+
+	location, err := service.GetLocationFromCEP(context.Background(), "01001000")
+
+	require.NoError(t, err)
+	require.NotNil(t, location.Localidade)
+	assert.Equal(t, city, *location.Localidade)
+	// Every provider has returned by now, so the loser already saw the cancellation
+	// Todos os provedores já retornaram, então o perdedor já viu o cancelamento
+	select {
+	case ctxErr := <-loser.ctxErr:
+		assert.ErrorIs(t, ctxErr, context.Canceled)
+	default:
+		t.Fatal("the losing provider was still running after GetLocationFromCEP returned")
+	}
+	assertNoLeakedGoroutines(t, baseline)
+}
+
+func TestGetLocationFromCEPCallerCancels(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	first := newStubCEPProvider("first", blockUntilCancelled)
+	second := newStubCEPProvider("second", blockUntilCancelled)
+	service := NewLocationService([]CEPProvider{first, second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := service.GetLocationFromCEP(ctx, "01001000")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	for _, provider := range []*stubCEPProvider{first, second} {
+		select {
+		case ctxErr := <-provider.ctxErr:
+			assert.ErrorIs(t, ctxErr, context.Canceled, provider.name)
+		default:
+			t.Fatalf("provider %s was still running after GetLocationFromCEP returned", provider.name)
+		}
+	}
+	assertNoLeakedGoroutines(t, baseline)
+}
+
+func TestAPIClientErrorsOmitTheQueryString(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Every request now fails with a transport error
+	client := NewAPIClient(&http.Client{}, nil, time.Second)
+	client.DefaultRetryPolicy.MaxAttempts = 1
+
+	_, err := client.Get(context.Background(), server.URL+"/v1/current.json?key=secret-key&q=Recife")
+
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-key")
+	assert.Contains(t, err.Error(), server.Listener.Addr().String())
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"service-b/cache"
+	"service-b/models"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Defaults for the weather cache, used when nothing is configured.
+// Valores padrão do cache de clima, usados quando nada é configurado.
+const (
+	DefaultWeatherCacheSize     = 1000
+	DefaultWeatherCacheTTL      = 5 * time.Minute
+	DefaultWeatherCacheMaxStale = time.Hour
+	weatherRefreshTimeout       = 10 * time.Second       // Bound for background refreshes
+	weatherStaleWait            = 250 * time.Millisecond // Longest a stale reading waits for its refresh
+	weatherRefreshBackoff       = time.Second            // Pause after a failed refresh, doubled on each failure
+	weatherRefreshMaxBackoff    = time.Minute            // Upper bound for the pause between refreshes
+)
+
+// cachedTemperature is a cached reading, fresh until freshUntil and stale afterwards.
+// Leitura em cache, atual até freshUntil e obsoleta depois disso.
+type cachedTemperature struct {
+	Celsius    float64   `json:"celsius"`
+	FreshUntil time.Time `json:"fresh_until"`
+}
+
+// CachedWeatherService wraps a WeatherService with a short-lived cache keyed by
+// normalized city/UF. Once an entry expires it is refreshed in the background and the
+// stale reading (up to maxStale old) is served unless the refresh answers within a
+// short wait. After a failed refresh the upstream is left alone for a growing backoff,
+// serving the stale reading meanwhile.
+// CachedWeatherService envolve um WeatherService com um cache de curta duração
+// indexado por cidade/UF normalizadas. Quando uma entrada expira ela é atualizada em
+// segundo plano e a leitura obsoleta (de até maxStale) é servida, a menos que a
+// atualização responda dentro de uma espera curta. Após uma atualização com falha o
+// upstream é poupado por um backoff crescente, servindo a leitura obsoleta nesse meio tempo.
+type CachedWeatherService struct {
+	next      WeatherService     // Service answering cache misses
+	cache     cache.Cache        // Cached readings indexed by city/UF
+	ttl       time.Duration      // Freshness of a reading
+	maxStale  time.Duration      // How long a reading may be served after it expired
+	group     singleflight.Group // Collapses concurrent refreshes of a key
+	staleWait time.Duration      // Longest a stale reading waits for its refresh
+
+	mu         sync.Mutex
+	backoff    time.Duration           // Pause after the first failed refresh of a key
+	maxBackoff time.Duration           // Upper bound for the pause
+	failures   map[string]refreshRetry // Keys whose last refresh failed
+}
+
+// refreshRetry tracks the failed refreshes of a key.
+// Acompanha as atualizações com falha de uma chave.
+type refreshRetry struct {
+	failures int       // Consecutive failed refreshes
+	retryAt  time.Time // No refresh is attempted before this
+}
+
+// NewCachedWeatherService creates a caching WeatherService in front of next.
+// Cria um WeatherService com cache na frente de next.
+func NewCachedWeatherService(next WeatherService, store cache.Cache, ttl, maxStale time.Duration) WeatherService {
+	return &CachedWeatherService{
+		next:       next,
+		cache:      store,
+		ttl:        ttl,
+		maxStale:   maxStale,
+		staleWait:  weatherStaleWait,
+		backoff:    weatherRefreshBackoff,
+		maxBackoff: weatherRefreshMaxBackoff,
+		failures:   map[string]refreshRetry{},
+	}
+}
+
+// GetTemperature answers from the cache when the reading is fresh and asks the
+// upstream on a miss. An expired reading is refreshed, served stale when the refresh
+// fails or takes longer than staleWait.
+// Responde pelo cache quando a leitura está atual e consulta o upstream quando não
+// há leitura. Uma leitura expirada é atualizada, servida obsoleta quando a
+// atualização falha ou demora mais que staleWait.
+func (c *CachedWeatherService) GetTemperature(ctx context.Context, city, uf string) (models.Temperature, error) {
+	span := trace.SpanFromContext(ctx)
+	key := weatherCacheKey(city, uf)
+
+	var cached cachedTemperature
+	ok := getCached(ctx, c.cache, key, &cached)
+	if ok && time.Now().Before(cached.FreshUntil) {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		metrics.recordCacheLookup(ctx, "weather", true)
+		return models.Temperature{Celsius: cached.Celsius}, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	metrics.recordCacheLookup(ctx, "weather", false)
+
+	if !ok {
+		temperature, err := c.next.GetTemperature(ctx, city, uf)
+		if err != nil {
+			return models.Temperature{}, err
+		}
+		c.refreshed(key, nil) // Forget the backoff of a reading that expired entirely
+		c.store(ctx, key, temperature.Celsius)
+		return temperature, nil
+	}
+
+	// The previous refresh failed recently, leave the upstream alone
+	// A atualização anterior falhou há pouco, poupa o upstream
+	if !c.refreshDue(key) {
+		span.SetAttributes(attribute.Bool("cache.stale", true), attribute.Bool("cache.refresh_backoff", true))
+		return models.Temperature{Celsius: cached.Celsius, Stale: true}, nil
+	}
+
+	wait := time.NewTimer(c.staleWait)
+	defer wait.Stop()
+	select {
+	case result := <-c.refresh(ctx, key, city, uf):
+		if result.Err == nil {
+			return models.Temperature{Celsius: result.Val.(float64)}, nil
+		}
+		span.RecordError(result.Err)
+	case <-wait.C:
+		// The refresh goes on in the background
+		// A atualização continua em segundo plano
+	case <-ctx.Done():
+		return models.Temperature{}, ctx.Err()
+	}
+	span.SetAttributes(attribute.Bool("cache.stale", true))
+	return models.Temperature{Celsius: cached.Celsius, Stale: true}, nil
+}
+
+// store caches a reading, keeping it around for maxStale after it expires.
+// Guarda uma leitura, mantendo-a por maxStale depois de expirar.
+func (c *CachedWeatherService) store(ctx context.Context, key string, celsius float64) {
+	setCached(ctx, c.cache, key, cachedTemperature{Celsius: celsius, FreshUntil: time.Now().Add(c.ttl)}, c.ttl+c.maxStale)
+}
+
+// refresh asks the upstream again, detached from the caller so it outlives a
+// stale answer; concurrent refreshes of the same key share a single call. The
+// outcome sets or clears the backoff of the key.
+// Consulta o upstream novamente, desligado de quem chama para sobreviver a uma
+// resposta obsoleta; atualizações simultâneas da mesma chave compartilham uma única
+// chamada. O resultado define ou limpa o backoff da chave.
+func (c *CachedWeatherService) refresh(ctx context.Context, key, city, uf string) <-chan singleflight.Result {
+	ctx = context.WithoutCancel(ctx)
+	return c.group.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, weatherRefreshTimeout)
+		defer cancel()
+		temperature, err := c.next.GetTemperature(ctx, city, uf)
+		c.refreshed(key, err)
+		if err != nil {
+			return nil, err
+		}
+		c.store(ctx, key, temperature.Celsius)
+		return temperature.Celsius, nil
+	})
+}
+
+// refreshDue tells whether the backoff of a key, if any, is over.
+// Informa se o backoff de uma chave, se houver, terminou.
+func (c *CachedWeatherService) refreshDue(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	retry, ok := c.failures[key]
+	return !ok || !time.Now().Before(retry.retryAt)
+}
+
+// refreshed records the outcome of a refresh, doubling the backoff on each failure.
+// Registra o resultado de uma atualização, dobrando o backoff a cada falha.
+func (c *CachedWeatherService) refreshed(key string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.failures, key)
+		return
+	}
+	retry := c.failures[key]
+	retry.failures++
+	delay := c.maxBackoff
+	if retry.failures < 32 {
+		delay = min(c.backoff<<(retry.failures-1), c.maxBackoff)
+	}
+	retry.retryAt = time.Now().Add(delay)
+	c.failures[key] = retry
+}
+
+// weatherCacheKey normalizes city and UF so "São Paulo"/"sp" and "sao paulo"/"SP"
+// share an entry.
+// Normaliza cidade e UF para que "São Paulo"/"sp" e "sao paulo"/"SP" compartilhem
+// a mesma entrada.
+func weatherCacheKey(city, uf string) string {
+	return "weather:" + normalize(city) + "/" + normalize(uf)
+}
+
+// normalize lowercases, trims and strips accents from a name.
+// Converte para minúsculas, remove espaços nas pontas e acentos de um nome.
+func normalize(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+	return strings.ToLower(strings.Join(strings.Fields(stripped), " "))
+}
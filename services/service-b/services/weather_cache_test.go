@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"service-b/cache"
+	"service-b/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errWeatherDown = errors.New("weather upstream down")
+
+// stubWeatherService answers with answer, counting the calls.
+// Responde com answer, contando as chamadas.
+type stubWeatherService struct {
+	calls  atomic.Int32
+	answer atomic.Value // func(ctx context.Context) (float64, error)
+}
+
+func newStubWeatherService(answer func(ctx context.Context) (float64, error)) *stubWeatherService {
+	s := &stubWeatherService{}
+	s.answer.Store(answer)
+	return s
+}
+
+func (s *stubWeatherService) set(answer func(ctx context.Context) (float64, error)) {
+	s.answer.Store(answer)
+}
+
+func (s *stubWeatherService) GetTemperature(ctx context.Context, _, _ string) (models.Temperature, error) {
+	s.calls.Add(1)
+	celsius, err := s.answer.Load().(func(ctx context.Context) (float64, error))(ctx)
+	return models.Temperature{Celsius: celsius}, err
+}
+
+func answerWith(celsius float64) func(context.Context) (float64, error) {
+	return func(context.Context) (float64, error) { return celsius, nil }
+}
+
+func answerError(context.Context) (float64, error) { return 0, errWeatherDown }
+
+// newExpiringWeatherCache caches readings for a millisecond and keeps them stale for an hour.
+// Guarda leituras por um milissegundo e as mantém obsoletas por uma hora.
+func newExpiringWeatherCache(next WeatherService) *CachedWeatherService {
+	return NewCachedWeatherService(next, cache.NewMemory(10), time.Millisecond, time.Hour).(*CachedWeatherService)
+}
+
+// expire caches a reading of celsius and lets it expire.
+// Guarda uma leitura de celsius e deixa que expire.
+func expire(t *testing.T, c *CachedWeatherService, upstream *stubWeatherService, celsius float64) {
+	t.Helper()
+	upstream.set(answerWith(celsius))
+	_, err := c.GetTemperature(context.Background(), "Recife", "PE")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	upstream.calls.Store(0)
+}
+
+func TestWeatherCacheServesFreshReadings(t *testing.T) {
+	upstream := newStubWeatherService(answerWith(25))
+	c := NewCachedWeatherService(upstream, cache.NewMemory(10), time.Hour, time.Hour)
+
+	for _, city := range []string{"São Paulo", "sao paulo", " SAO  PAULO "} {
+		temperature, err := c.GetTemperature(context.Background(), city, "sp")
+		require.NoError(t, err)
+		assert.Equal(t, models.Temperature{Celsius: 25}, temperature)
+	}
+	assert.EqualValues(t, 1, upstream.calls.Load(), "normalized names share an entry")
+}
+
+func TestWeatherCacheMissReturnsTheUpstreamError(t *testing.T) {
+	c := newExpiringWeatherCache(newStubWeatherService(answerError))
+
+	_, err := c.GetTemperature(context.Background(), "Recife", "PE")
+
+	assert.ErrorIs(t, err, errWeatherDown)
+}
+
+func TestWeatherCacheRefreshesAnExpiredReading(t *testing.T) {
+	upstream := newStubWeatherService(nil)
+	c := newExpiringWeatherCache(upstream)
+	expire(t, c, upstream, 25)
+	upstream.set(answerWith(28))
+
+	temperature, err := c.GetTemperature(context.Background(), "Recife", "PE")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.Temperature{Celsius: 28}, temperature, "a quick refresh is served fresh")
+}
+
+func TestWeatherCacheServesStaleWhileTheUpstreamFails(t *testing.T) {
+	upstream := newStubWeatherService(nil)
+	c := newExpiringWeatherCache(upstream)
+	expire(t, c, upstream, 25)
+	upstream.set(answerError)
+
+	temperature, err := c.GetTemperature(context.Background(), "Recife", "PE")
+	require.NoError(t, err)
+	assert.Equal(t, models.Temperature{Celsius: 25, Stale: true}, temperature)
+
+	// Within the backoff the upstream that just failed is not asked again
+	for range 3 {
+		temperature, err = c.GetTemperature(context.Background(), "Recife", "PE")
+		require.NoError(t, err)
+		assert.True(t, temperature.Stale)
+	}
+	assert.EqualValues(t, 1, upstream.calls.Load(), "one refresh per backoff")
+}
+
+func TestWeatherCacheRetriesTheRefreshAfterTheBackoff(t *testing.T) {
+	upstream := newStubWeatherService(nil)
+	c := newExpiringWeatherCache(upstream)
+	c.backoff, c.maxBackoff = 20*time.Millisecond, 40*time.Millisecond
+	expire(t, c, upstream, 25)
+	upstream.set(answerError)
+
+	c.GetTemperature(context.Background(), "Recife", "PE")
+	time.Sleep(c.backoff)
+	c.GetTemperature(context.Background(), "Recife", "PE")
+	require.EqualValues(t, 2, upstream.calls.Load())
+
+	// The second failure doubles the backoff
+	time.Sleep(c.backoff)
+	c.GetTemperature(context.Background(), "Recife", "PE")
+	assert.EqualValues(t, 2, upstream.calls.Load(), "still within the doubled backoff")
+
+	// Once the upstream is back the fresh reading replaces the stale one
+	upstream.set(answerWith(30))
+	time.Sleep(c.maxBackoff)
+	temperature, err := c.GetTemperature(context.Background(), "Recife", "PE")
+	require.NoError(t, err)
+	assert.Equal(t, models.Temperature{Celsius: 30}, temperature)
+}
+
+func TestWeatherCacheDoesNotWaitForASlowRefresh(t *testing.T) {
+	upstream := newStubWeatherService(nil)
+	c := newExpiringWeatherCache(upstream)
+	c.staleWait = 20 * time.Millisecond
+	expire(t, c, upstream, 25)
+	release := make(chan struct{})
+	upstream.set(func(context.Context) (float64, error) {
+		<-release
+		return 28, nil
+	})
+
+	start := time.Now()
+	temperature, err := c.GetTemperature(context.Background(), "Recife", "PE")
+	require.NoError(t, err)
+	assert.Equal(t, models.Temperature{Celsius: 25, Stale: true}, temperature)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the stale reading is served within the wait")
+
+	// Requests arriving meanwhile join the running refresh
+	c.GetTemperature(context.Background(), "Recife", "PE")
+	assert.EqualValues(t, 1, upstream.calls.Load())
+
+	// The background refresh stores the new reading, kept fresh this time
+	c.ttl = time.Hour
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if temperature, _ = c.GetTemperature(context.Background(), "Recife", "PE"); !temperature.Stale {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, 28.0, temperature.Celsius)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"service-b/models"
+	"sort"
+	"strings"
+)
+
+// DefaultWeatherProviders is the failover order used when none is configured.
+// Ordem de failover usada quando nenhuma é configurada.
+var DefaultWeatherProviders = []string{"weatherapi", "open-meteo"}
+
+// WeatherProvider defines a backend able to return the current temperature of a city.
+// WeatherProvider define um backend capaz de retornar a temperatura atual de uma cidade.
+type WeatherProvider interface {
+	Name() string                                                     // Name used in config and error messages.
+	GetTemperature(ctx context.Context, city string) (float64, error) // Temperature in Celsius.
+}
+
+// WeatherProviderKeys holds the credentials of the backends that need one.
+// WeatherProviderKeys guarda as credenciais dos backends que precisam de uma.
+type WeatherProviderKeys struct {
+	WeatherAPI     string // Key for api.weatherapi.com
+	OpenWeatherMap string // Key for api.openweathermap.org
+}
+
+// WeatherProviderFactory builds a WeatherProvider on top of an APIClient.
+// WeatherProviderFactory constrói um WeatherProvider a partir de um APIClient.
+type WeatherProviderFactory func(client APIClient, keys WeatherProviderKeys) WeatherProvider
+
+// WeatherProviderRegistry keeps the known weather backends indexed by name.
+// WeatherProviderRegistry mantém os backends de clima conhecidos indexados por nome.
+type WeatherProviderRegistry struct {
+	factories map[string]WeatherProviderFactory // Provider factories indexed by name
+}
+
+// NewWeatherProviderRegistry creates an empty registry.
+// Cria um registro vazio.
+func NewWeatherProviderRegistry() *WeatherProviderRegistry {
+	return &WeatherProviderRegistry{
+		factories: map[string]WeatherProviderFactory{}, // Start without any provider
+	}
+}
+
+// DefaultWeatherProviderRegistry creates a registry with every built-in backend.
+// Cria um registro com todos os backends embutidos.
+func DefaultWeatherProviderRegistry() *WeatherProviderRegistry {
+	registry := NewWeatherProviderRegistry()
+	registry.Register("weatherapi", func(client APIClient, keys WeatherProviderKeys) WeatherProvider {
+		return &WeatherAPIProvider{Client: client, APIKey: keys.WeatherAPI}
+	})
+	registry.Register("open-meteo", func(client APIClient, _ WeatherProviderKeys) WeatherProvider {
+		return &OpenMeteoProvider{Client: client}
+	})
+	registry.Register("openweathermap", func(client APIClient, keys WeatherProviderKeys) WeatherProvider {
+		return &OpenWeatherMapProvider{Client: client, APIKey: keys.OpenWeatherMap}
+	})
+	return registry
+}
+
+// Register adds or replaces a provider factory under the given name.
+// Adiciona ou substitui a fábrica de um provedor com o nome informado.
+func (r *WeatherProviderRegistry) Register(name string, factory WeatherProviderFactory) {
+	r.factories[strings.ToLower(name)] = factory
+}
+
+// Unregister removes a provider factory from the registry.
+// Remove a fábrica de um provedor do registro.
+func (r *WeatherProviderRegistry) Unregister(name string) {
+	delete(r.factories, strings.ToLower(name))
+}
+
+// Names returns the registered provider names in alphabetical order.
+// Retorna os nomes dos provedores registrados em ordem alfabética.
+func (r *WeatherProviderRegistry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build instantiates the named providers in the given failover order.
+// Instancia os provedores informados na ordem de failover recebida.
+func (r *WeatherProviderRegistry) Build(names []string, client APIClient, keys WeatherProviderKeys) ([]WeatherProvider, error) {
+	providers := make([]WeatherProvider, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue // Skip blanks and duplicates
+		}
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown weather provider %q (available: %s)", name, strings.Join(r.Names(), ", "))
+		}
+		seen[name] = true
+		providers = append(providers, factory(client, keys))
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("at least one weather provider must be configured")
+	}
+	return providers, nil
+}
+
+// errMissingAPIKey is returned by keyed backends that were not given a key.
+// Retornado por backends que exigem chave quando nenhuma foi informada.
+var errMissingAPIKey = errors.New("missing api key")
+
+// WeatherAPIProvider fetches the temperature from api.weatherapi.com.
+// Busca a temperatura em api.weatherapi.com.
+type WeatherAPIProvider struct {
+	Client APIClient // The API client used for making requests.
+	APIKey string    // WeatherAPI key
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *WeatherAPIProvider) Name() string { return "weatherapi" }
+
+// GetTemperature retrieves the current temperature for a given city.
+// Recupera a temperatura atual para uma cidade específica.
+func (p *WeatherAPIProvider) GetTemperature(ctx context.Context, city string) (float64, error) {
+	if p.APIKey == "" {
+		return 0, errMissingAPIKey
+	}
+	// Fix spaces on names
+	encodedCity := url.QueryEscape(city) // Encode the city name to ensure it works in a URL
+	url := fmt.Sprintf("https://api.weatherapi.com/v1/current.json?key=%s&q=%s", p.APIKey, encodedCity)
+
+	var weather models.WeatherResponse
+	if err := getJSON(ctx, p.Client, url, &weather); err != nil {
+		return 0, err
+	}
+
+	return weather.Current.TempC, nil // Return the temperature in Celsius
+}
+
+// OpenMeteoProvider fetches the temperature from Open-Meteo. It needs no key and
+// works on coordinates, so the city is geocoded first.
+// Busca a temperatura no Open-Meteo. Não exige chave e trabalha com coordenadas,
+// por isso a cidade é geocodificada antes.
+type OpenMeteoProvider struct {
+	Client APIClient // The API client used for making requests.
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *OpenMeteoProvider) Name() string { return "open-meteo" }
+
+// GetTemperature retrieves the current temperature for a given city.
+// Recupera a temperatura atual para uma cidade específica.
+func (p *OpenMeteoProvider) GetTemperature(ctx context.Context, city string) (float64, error) {
+	geocodingURL := fmt.Sprintf("https://geocoding-api.open-meteo.com/v1/search?name=%s&count=1&language=pt&countryCode=BR&format=json", url.QueryEscape(city))
+
+	var places models.OpenMeteoGeocodingResponse
+	if err := getJSON(ctx, p.Client, geocodingURL, &places); err != nil {
+		return 0, err
+	}
+	if len(places.Results) == 0 {
+		return 0, fmt.Errorf("city %q not found", city)
+	}
+
+	place := places.Results[0]
+	forecastURL := fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f&current=temperature_2m", place.Latitude, place.Longitude)
+
+	var forecast models.OpenMeteoForecastResponse
+	if err := getJSON(ctx, p.Client, forecastURL, &forecast); err != nil {
+		return 0, err
+	}
+
+	return forecast.Current.Temperature2m, nil // Return the temperature in Celsius
+}
+
+// OpenWeatherMapProvider fetches the temperature from api.openweathermap.org.
+// Busca a temperatura em api.openweathermap.org.
+type OpenWeatherMapProvider struct {
+	Client APIClient // The API client used for making requests.
+	APIKey string    // OpenWeatherMap key
+}
+
+// Name returns the provider name.
+// Retorna o nome do provedor.
+func (p *OpenWeatherMapProvider) Name() string { return "openweathermap" }
+
+// GetTemperature retrieves the current temperature for a given city.
+// Recupera a temperatura atual para uma cidade específica.
+func (p *OpenWeatherMapProvider) GetTemperature(ctx context.Context, city string) (float64, error) {
+	if p.APIKey == "" {
+		return 0, errMissingAPIKey
+	}
+	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?q=%s,BR&units=metric&appid=%s", url.QueryEscape(city), p.APIKey)
+
+	var weather models.OpenWeatherMapResponse
+	if err := getJSON(ctx, p.Client, url, &weather); err != nil {
+		return 0, err
+	}
+
+	return weather.Main.Temp, nil // Return the temperature in Celsius
+}